@@ -0,0 +1,262 @@
+package transform
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"errors"
+)
+
+// checkpointVersion is the version of the binary format of checkpoints.
+const checkpointVersion = 1
+
+var (
+	// ErrInvalidCheckpoint is returned when a checkpoint cannot be decoded.
+	ErrInvalidCheckpoint = errors.New("transform: invalid checkpoint")
+	// ErrCheckpointMismatch is returned when a checkpoint is restored into
+	// a Replacer which has different patterns from the checkpointed one.
+	ErrCheckpointMismatch = errors.New("transform: checkpoint does not match the Replacer")
+)
+
+var (
+	_ encoding.BinaryMarshaler   = (*Replacer)(nil)
+	_ encoding.BinaryUnmarshaler = (*Replacer)(nil)
+	_ encoding.BinaryMarshaler   = (*ReplaceHistory)(nil)
+	_ encoding.BinaryUnmarshaler = (*ReplaceHistory)(nil)
+)
+
+// Offset returns the number of bytes which the Replacer has consumed from
+// the source and the number of bytes which it has written to the destination.
+//
+// Bytes which are consumed but not transformed yet are kept in the Replacer,
+// so a checkpoint taken by MarshalBinary can be resumed by feeding
+// the source from src and writing the destination from dst.
+func (r *Replacer) Offset() (src, dst int) {
+	return r.offSrc + len(r.preSrc), r.offDst
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+// It takes a checkpoint of the internal state of the Replacer,
+// including its history if the history implements encoding.BinaryMarshaler
+// such as *ReplaceHistory.
+//
+// The checkpoint can be restored into a new Replacer which is created
+// with the same old, new and flags by UnmarshalBinary.
+func (r *Replacer) MarshalBinary() ([]byte, error) {
+	var e checkpointEncoder
+	e.uint(checkpointVersion)
+	e.bytes(r.old)
+	e.bytes(r.new)
+	e.uint(uint64(r.flags))
+	e.bytes(r.preSrc)
+	e.bytes(r.preDst)
+	e.uint(uint64(r.offSrc))
+	e.uint(uint64(r.offDst))
+	var midLine uint64
+	if r.midLine {
+		midLine = 1
+	}
+	e.uint(midLine)
+	var inRun uint64
+	if r.preState.inRun {
+		inRun = 1
+	}
+	e.uint(uint64(r.preState.i))
+	e.uint(inRun)
+
+	m, ok := r.history.(encoding.BinaryMarshaler)
+	if !ok {
+		e.uint(0)
+		return e.buf, nil
+	}
+
+	h, err := m.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	e.uint(1)
+	e.bytes(h)
+
+	return e.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It restores a checkpoint which is taken by MarshalBinary.
+// The Replacer must be created with the same old, new and flags as the checkpointed one,
+// otherwise UnmarshalBinary returns ErrCheckpointMismatch.
+//
+// If the checkpoint has a history and the history of the Replacer
+// implements encoding.BinaryUnmarshaler, the history is also restored.
+func (r *Replacer) UnmarshalBinary(data []byte) error {
+	d := checkpointDecoder{buf: data}
+	if d.uint() != checkpointVersion {
+		return ErrInvalidCheckpoint
+	}
+
+	old, new := d.bytes(), d.bytes()
+	flags := ReplaceFlag(d.int())
+	preSrc, preDst := d.bytes(), d.bytes()
+	offSrc, offDst := d.int(), d.int()
+	midLine := d.uint() == 1
+	preState := flagsState{i: d.int(), inRun: d.uint() == 1}
+	hasHistory := d.uint() == 1
+	var h []byte
+	if hasHistory {
+		h = d.bytes()
+	}
+
+	if d.err != nil || len(d.buf) != 0 {
+		return ErrInvalidCheckpoint
+	}
+
+	if !bytes.Equal(old, r.old) || !bytes.Equal(new, r.new) || flags != r.flags {
+		return ErrCheckpointMismatch
+	}
+
+	if preState.i > len(old) {
+		return ErrInvalidCheckpoint
+	}
+
+	if u, ok := r.history.(encoding.BinaryUnmarshaler); hasHistory && ok {
+		if err := u.UnmarshalBinary(h); err != nil {
+			return err
+		}
+	}
+
+	r.preSrc = preSrc
+	r.preDst = preDst
+	r.offSrc = offSrc
+	r.offDst = offDst
+	r.midLine = midLine
+	r.preState = preState
+
+	return nil
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler.
+func (h *ReplaceHistory) MarshalBinary() ([]byte, error) {
+	h.lock()
+	defer h.unlock()
+
+	var e checkpointEncoder
+	e.uint(checkpointVersion)
+	e.uint(uint64(len(h.src0)))
+	for i := range h.src0 {
+		e.uint(uint64(h.src0[i]))
+		e.uint(uint64(h.src1[i]))
+		e.uint(uint64(h.dst0[i]))
+		e.uint(uint64(h.dst1[i]))
+	}
+	e.uint(uint64(len(h.labels)))
+	for _, label := range h.labels {
+		e.bytes([]byte(label))
+	}
+	return e.buf, nil
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+// It replaces the histories with the decoded ones.
+func (h *ReplaceHistory) UnmarshalBinary(data []byte) error {
+	d := checkpointDecoder{buf: data}
+	if d.uint() != checkpointVersion {
+		return ErrInvalidCheckpoint
+	}
+
+	n := d.int()
+	// each history needs at least 4 bytes
+	if d.err != nil || n > len(d.buf)/4 {
+		return ErrInvalidCheckpoint
+	}
+
+	var _h ReplaceHistory
+	for i := 0; i < n; i++ {
+		_h.add(d.int(), d.int(), d.int(), d.int())
+	}
+
+	// labels are recorded for all histories or none of them
+	if m := d.int(); m != 0 {
+		if m != n {
+			return ErrInvalidCheckpoint
+		}
+		_h.labels = make([]string, n)
+		for i := range _h.labels {
+			_h.labels[i] = string(d.bytes())
+		}
+	}
+
+	if d.err != nil || len(d.buf) != 0 {
+		return ErrInvalidCheckpoint
+	}
+
+	h.lock()
+	defer h.unlock()
+	h.src0, h.src1 = _h.src0, _h.src1
+	h.dst0, h.dst1 = _h.dst0, _h.dst1
+	h.labels = _h.labels
+
+	return nil
+}
+
+type checkpointEncoder struct {
+	buf []byte
+}
+
+func (e *checkpointEncoder) uint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+func (e *checkpointEncoder) bytes(b []byte) {
+	e.uint(uint64(len(b)))
+	e.buf = append(e.buf, b...)
+}
+
+type checkpointDecoder struct {
+	buf []byte
+	err error
+}
+
+func (d *checkpointDecoder) uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.err = ErrInvalidCheckpoint
+		return 0
+	}
+	d.buf = d.buf[n:]
+
+	return v
+}
+
+func (d *checkpointDecoder) int() int {
+	v := d.uint()
+	if v > uint64(^uint(0)>>1) {
+		d.err = ErrInvalidCheckpoint
+		return 0
+	}
+	return int(v)
+}
+
+func (d *checkpointDecoder) bytes() []byte {
+	n := d.uint()
+	if d.err != nil {
+		return nil
+	}
+
+	if n > uint64(len(d.buf)) {
+		d.err = ErrInvalidCheckpoint
+		return nil
+	}
+
+	if n == 0 {
+		return nil
+	}
+
+	b := make([]byte, n)
+	copy(b, d.buf)
+	d.buf = d.buf[n:]
+
+	return b
+}
@@ -0,0 +1,97 @@
+package transform_test
+
+import (
+	"bytes"
+	"testing"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestReplacer_MarshalBinary(t *testing.T) {
+	chunks := [][]byte{
+		[]byte(`abcab`),
+		[]byte(`cxxab`),
+		[]byte(`cabca`),
+		[]byte(`bc`),
+	}
+
+	old, new := []byte(`abc`), []byte(`ABCDE`)
+	for i := 1; i < len(chunks); i++ {
+		var out bytes.Buffer
+		history := NewReplaceHistory()
+		r := NewReplacer(old, new, history)
+		for _, c := range chunks[:i] {
+			out.Write(transformChunk(t, r, c, false))
+		}
+
+		checkpoint, err := r.MarshalBinary()
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		nSrc, nDst := r.Offset()
+		var consumed int
+		for _, c := range chunks[:i] {
+			consumed += len(c)
+		}
+		if nSrc != consumed || nDst != out.Len() {
+			t.Errorf("chunks[:%d]: the offset is expected (%d, %d) but (%d, %d)", i, consumed, out.Len(), nSrc, nDst)
+		}
+
+		// resume from the checkpoint
+		history = NewReplaceHistory()
+		r = NewReplacer(old, new, history)
+		if err := r.UnmarshalBinary(checkpoint); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+
+		for j, c := range chunks[i:] {
+			out.Write(transformChunk(t, r, c, i+j == len(chunks)-1))
+		}
+
+		const expected = `ABCDEABCDExxABCDEABCDEABCDE`
+		if out.String() != expected {
+			t.Errorf("chunks[:%d]: the output is expected %q but %q", i, expected, out.String())
+		}
+
+		var n int
+		history.Iterate(func(src0, src1, dst0, dst1 int) bool {
+			n++
+			return true
+		})
+		if n != 5 {
+			t.Errorf("chunks[:%d]: the number of histories is expected 5 but %d", i, n)
+		}
+
+		src0, src1, dst0, dst1 := history.At(4)
+		if src0 != 14 || src1 != 17 || dst0 != 22 || dst1 != 27 {
+			t.Errorf("chunks[:%d]: unexpected history[4] (%d, %d, %d, %d)", i, src0, src1, dst0, dst1)
+		}
+	}
+}
+
+func TestReplacer_UnmarshalBinary(t *testing.T) {
+	checkpoint, err := ReplaceString("abc", "ABC").MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if err := ReplaceString("abc", "XYZ").UnmarshalBinary(checkpoint); err != ErrCheckpointMismatch {
+		t.Errorf("the error is expected %v but %v", ErrCheckpointMismatch, err)
+	}
+
+	if err := ReplaceString("abc", "ABC").UnmarshalBinary(checkpoint[:len(checkpoint)-1]); err != ErrInvalidCheckpoint {
+		t.Errorf("the error is expected %v but %v", ErrInvalidCheckpoint, err)
+	}
+}
+
+// transformChunk transforms a chunk with a large enough dst.
+func transformChunk(t *testing.T, r *Replacer, src []byte, atEOF bool) []byte {
+	t.Helper()
+	dst := make([]byte, 100)
+	nDst, nSrc, err := r.Transform(dst, src, atEOF)
+	if nSrc != len(src) {
+		t.Fatalf("the nSrc is expected %d but %d (err: %v)", len(src), nSrc, err)
+	}
+	return dst[:nDst]
+}
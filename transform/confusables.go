@@ -0,0 +1,184 @@
+package transform
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+//go:generate go run gen_confusables.go -url https://www.unicode.org/Public/security/15.1.0/confusables.txt -output confusables_table.go
+
+// Skeleton returns the skeleton of s, in which each confusable rune
+// such as Cyrillic "а" (U+0430) is mapped to its prototype such as Latin "a".
+// Two strings which look alike have the same skeleton.
+//
+// Like the skeleton of UTS #39, s is normalized to NFD before mapping
+// and the result is normalized to NFD again,
+// so "café" in NFC and in NFD have the same skeleton.
+func Skeleton(s string) string {
+	return string(appendSkeleton(nil, []byte(s)))
+}
+
+// appendSkeleton appends the skeleton of s to b.
+func appendSkeleton(b, s []byte) []byte {
+	// fast path for an ASCII rune, which is not changed by NFD
+	if len(s) == 1 && s[0] < utf8.RuneSelf {
+		if p, ok := confusables[rune(s[0])]; ok {
+			return append(b, p...)
+		}
+		return append(b, s[0])
+	}
+
+	var mapped []byte
+	for d := norm.NFD.Bytes(s); len(d) > 0; {
+		r, n := utf8.DecodeRune(d)
+		if p, ok := confusables[r]; ok && !(r == utf8.RuneError && n == 1) {
+			mapped = append(mapped, p...)
+		} else {
+			mapped = append(mapped, d[:n]...)
+		}
+		d = d[n:]
+	}
+
+	return norm.NFD.Append(b, mapped...)
+}
+
+// ConfusableTransformer maps confusable runes to their skeletons,
+// or replaces byte data whose skeleton matches the skeleton of a replacing rule.
+// It implements transform.Transformer.
+type ConfusableTransformer struct {
+	rules *ReplaceRules // nil for skeleton mode; old of rules are skeletons
+	w     streamWriter
+}
+
+var _ transform.Transformer = (*ConfusableTransformer)(nil)
+
+// NewSkeletonTransformer creates a new ConfusableTransformer which maps input to its skeleton.
+// The input is mapped by normalization segments, such as "e" followed by U+0301.
+//
+// If history is not nil, ConfusableTransformer records histories of mapped segments.
+func NewSkeletonTransformer(history HistorySink) *ConfusableTransformer {
+	return &ConfusableTransformer{
+		w: newStreamWriter(history),
+	}
+}
+
+// NewConfusableReplacer creates a new ConfusableTransformer which replaces byte data
+// whose skeleton matches the skeleton of old of a replacing rule to its new.
+// Byte data which does not match is copied as it is, not as its skeleton.
+// At each position the first rule in the table which matches is applied.
+// For example, with a rule "paypal" to "***", "pаypаl" with Cyrillic "а" becomes "***".
+//
+// If history is not nil, ConfusableTransformer records histories of replacing,
+// whose source ranges are the matched byte data in the original input.
+func NewConfusableReplacer(t ReplaceTable, history HistorySink) *ConfusableTransformer {
+	rules := CompileReplaceTable(t)
+	for i, old := range rules.old {
+		rules.old[i] = []byte(Skeleton(string(old)))
+	}
+
+	return &ConfusableTransformer{
+		rules: rules,
+		w:     newStreamWriter(history),
+	}
+}
+
+// Reset implements transform.Transformer.Reset.
+func (t *ConfusableTransformer) Reset() {
+	t.w.reset()
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When end of src may be a part of a match or an incomplete normalization segment and atEOF is false,
+// the ConfusableTransformer stops to transform and returns transform.ErrShortSrc.
+func (t *ConfusableTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { t.w.done(nDst, nSrc) }()
+
+	nDst, err = t.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	var skel []byte
+	for nSrc < len(src) {
+		if t.rules != nil {
+			n, rule, short := t.match(src[nSrc:], atEOF)
+			if short {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+
+			if rule != -1 {
+				_, new := t.rules.At(rule)
+				nDst, err = t.w.replace(dst, nDst, nSrc, nSrc+n, new)
+				nSrc += n
+				if err != nil {
+					return nDst, nSrc, err
+				}
+				continue
+			}
+		}
+
+		n := norm.NFD.NextBoundary(src[nSrc:], atEOF)
+		if n == -1 {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+
+		seg := src[nSrc : nSrc+n]
+		if t.rules == nil {
+			skel = appendSkeleton(skel[:0], seg)
+		}
+		if t.rules != nil || bytes.Equal(skel, seg) {
+			nDst, nSrc, err = t.w.copy(dst, src, nDst, nSrc, nSrc+n)
+		} else {
+			nDst, err = t.w.replace(dst, nDst, nSrc, nSrc+n, skel)
+			nSrc += n
+		}
+
+		if err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	return nDst, nSrc, nil
+}
+
+// match returns the index of the first rule whose skeleton matches
+// the skeleton of the beginning of src, and the length of the matched bytes in src.
+// src is matched by normalization segments, so a match does not end in the middle of a segment.
+// If no rule matches, rule is -1.
+func (t *ConfusableTransformer) match(src []byte, atEOF bool) (n, rule int, short bool) {
+	var skel []byte
+	for i, old := range t.rules.old {
+		if len(old) == 0 {
+			continue
+		}
+
+		n, k := 0, 0 // matched bytes in src and old
+		for k < len(old) && n < len(src) {
+			size := norm.NFD.NextBoundary(src[n:], atEOF)
+			if size == -1 {
+				return 0, -1, true
+			}
+
+			skel = appendSkeleton(skel[:0], src[n:n+size])
+			if !bytes.HasPrefix(old[k:], skel) {
+				break
+			}
+			n += size
+			k += len(skel)
+		}
+
+		if k == len(old) {
+			return n, i, false
+		}
+
+		if k < len(old) && n == len(src) && !atEOF {
+			return 0, -1, true
+		}
+	}
+
+	return 0, -1, false
+}
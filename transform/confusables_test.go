@@ -0,0 +1,107 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestSkeleton(t *testing.T) {
+	cases := []struct {
+		s, expected string
+	}{
+		{"pаypаl", "paypal"},
+		{"Раss", "Pass"},
+		{"I1l|", "llll"},
+		{"mom", "rnorn"},
+		{"\U0001D41A\U0001D41B\uFF43", "abc"},
+		{"\u13A0\u0441", "Dc"},
+		// s is normalized to NFD
+		{"\u00F6", "o\u0308"},
+		{"o\u0308", "o\u0308"},
+		{"caf\u00E9", "cafe\u0301"},
+		{"cafe\u0301", "cafe\u0301"},
+		{"漢字", "漢字"},
+	}
+
+	for _, c := range cases {
+		if got := Skeleton(c.s); got != c.expected {
+			t.Errorf("Skeleton(%q) is expected %q but %q", c.s, c.expected, got)
+		}
+	}
+}
+
+func TestConfusableTransformer_Transform(t *testing.T) {
+	cases := []struct {
+		table ReplaceTable
+		input string
+
+		expected string
+		history  []HistoryEntry
+	}{
+		{
+			input:    "pаypаl ο",
+			expected: "paypal o",
+			history:  []HistoryEntry{{1, 3, 1, 2}, {5, 7, 4, 5}, {9, 11, 7, 8}},
+		},
+		{
+			table:    ReplaceStringTable{"paypal", "***", "l", "L"},
+			input:    "pаypаl paypal payрalа",
+			expected: "*** *** ***а",
+			history:  []HistoryEntry{{0, 8, 0, 3}, {9, 15, 4, 7}, {16, 23, 8, 11}},
+		},
+		{
+			// non-matching bytes are copied as they are
+			table:    ReplaceStringTable{"admin", "user"},
+			input:    "аdm1n аdrnin",
+			expected: "аdm1n user",
+			history:  []HistoryEntry{{7, 14, 7, 11}},
+		},
+		{
+			// NFC and NFD have the same skeleton
+			input:    "caf\u00E9 cafe\u0301",
+			expected: "cafe\u0301 cafe\u0301",
+			history:  []HistoryEntry{{3, 5, 3, 6}},
+		},
+		{
+			// a match does not end in the middle of "e\u0301"
+			table:    ReplaceStringTable{"caf\u00E9", "X", "cafe", "Y"},
+			input:    "caf\u00E9 cafe\u0301 cafe",
+			expected: "X X Y",
+			history:  []HistoryEntry{{0, 5, 0, 1}, {6, 12, 2, 3}, {13, 17, 4, 5}},
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {9, 1}, {10, 3}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				sink := HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				})
+
+				var tr *ConfusableTransformer
+				if c.table == nil {
+					tr = NewSkeletonTransformer(sink)
+				} else {
+					tr = NewConfusableReplacer(c.table, sink)
+				}
+
+				got, err := transformChunks(tr, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"golang.org/x/text/transform"
+)
+
+// ErrEditOutOfRange is returned by Editor when an edit is beyond the end of the stream.
+var ErrEditOutOfRange = errors.New("transform: edit is out of range of the source")
+
+// Edit represents an edit of text which replaces src[Start:End] to New.
+// If Start equals End, the edit inserts New at Start.
+type Edit struct {
+	Start, End int
+	New        []byte
+}
+
+// EditsFromHistory creates edits from histories and the destination of the replacing.
+// Applying the edits to the source of the replacing makes the same destination.
+func EditsFromHistory(history *ReplaceHistory, dst []byte) []Edit {
+	var edits []Edit
+	history.Iterate(func(src0, src1, dst0, dst1 int) bool {
+		edits = append(edits, Edit{Start: src0, End: src1, New: dst[dst0:dst1]})
+		return true
+	})
+	return edits
+}
+
+// Editor applies edits to a stream in one pass.
+// It implements transform.Transformer.
+type Editor struct {
+	edits []Edit
+	w     streamWriter
+	next  int  // index of the next edit
+	skip  bool // whether the Editor is skipping bytes of edits[next-1]
+}
+
+var _ transform.Transformer = (*Editor)(nil)
+
+// NewEditor creates a new Editor which applies edits.
+// The edits are sorted by their ranges and must not overlap each other.
+// Insertions at the same position are applied in the given order.
+// NewEditor returns an error if an edit has an invalid range or edits overlap.
+//
+// If history is not nil, Editor records histories of editing.
+func NewEditor(edits []Edit, history HistorySink) (*Editor, error) {
+	sorted := make([]Edit, len(edits))
+	copy(sorted, edits)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Start != sorted[j].Start {
+			return sorted[i].Start < sorted[j].Start
+		}
+		// an insertion is applied before a replacement at the same position
+		return sorted[i].End == sorted[i].Start && sorted[j].End != sorted[j].Start
+	})
+
+	for i, e := range sorted {
+		if e.Start < 0 || e.Start > e.End {
+			return nil, fmt.Errorf("transform: edit has invalid range [%d:%d]", e.Start, e.End)
+		}
+
+		if i > 0 && sorted[i-1].End > e.Start {
+			prev := sorted[i-1]
+			return nil, fmt.Errorf("transform: edit [%d:%d] overlaps edit [%d:%d]", e.Start, e.End, prev.Start, prev.End)
+		}
+	}
+
+	return &Editor{
+		edits: sorted,
+		w:     newStreamWriter(history),
+	}, nil
+}
+
+// Reset implements transform.Transformer.Reset.
+func (e *Editor) Reset() {
+	e.w.reset()
+	e.next = 0
+	e.skip = false
+}
+
+// Transform implements transform.Transformer.Transform.
+// Transform applies the edits to src and copies to dst.
+//
+// The Editor does not need to hold bytes which are replaced by an edit
+// even if the bytes lie across src buffers.
+// If atEOF is true and some edits are remaining, Transform returns ErrEditOutOfRange.
+func (e *Editor) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { e.w.done(nDst, nSrc) }()
+
+	nDst, err = e.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	for {
+		pos := e.w.offSrc + nSrc
+
+		if e.skip {
+			end := e.edits[e.next-1].End
+			n := end - pos
+			if n > len(src)-nSrc {
+				if atEOF {
+					return nDst, len(src), ErrEditOutOfRange
+				}
+				return nDst, len(src), nil
+			}
+			nSrc += n
+			e.skip = false
+			continue
+		}
+
+		if e.next >= len(e.edits) {
+			return e.w.copy(dst, src, nDst, nSrc, len(src))
+		}
+
+		edit := e.edits[e.next]
+		if edit.Start > pos {
+			end := nSrc + edit.Start - pos
+			if end > len(src) {
+				end = len(src)
+			}
+
+			if nDst, nSrc, err = e.w.copy(dst, src, nDst, nSrc, end); err != nil {
+				return nDst, nSrc, err
+			}
+
+			if e.w.offSrc+nSrc < edit.Start {
+				if atEOF {
+					return nDst, nSrc, ErrEditOutOfRange
+				}
+				return nDst, nSrc, nil
+			}
+			continue
+		}
+
+		e.next++
+		e.skip = edit.End > edit.Start
+		e.w.record(nSrc, nSrc+edit.End-edit.Start, nDst, nDst+len(edit.New))
+		if nDst, err = e.w.write(dst, nDst, edit.New); err != nil {
+			return nDst, nSrc, err
+		}
+	}
+}
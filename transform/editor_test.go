@@ -0,0 +1,141 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func ExampleEditor() {
+	e, err := NewEditor([]Edit{
+		{Start: 7, End: 12, New: []byte("Gophers")},
+		{Start: 0, End: 5, New: []byte("Hi")},
+	}, nil)
+	if err != nil {
+		panic(err)
+	}
+	s, _, _ := transform.String(e, "Hello, World!")
+	fmt.Println(s)
+	// Output: Hi, Gophers!
+}
+
+func TestEditor_Transform(t *testing.T) {
+	cases := []struct {
+		edits []Edit
+		input string
+
+		expected string
+		history  []HistoryEntry
+		err      error
+	}{
+		{
+			edits: []Edit{
+				{Start: 1, End: 3, New: []byte("XYZ")},
+				{Start: 3, End: 3, New: []byte("+")},
+				{Start: 0, End: 0, New: []byte("<")},
+				{Start: 6, End: 6, New: []byte(">")},
+			},
+			input:    "abcdef",
+			expected: "<aXYZ+def>",
+			history:  []HistoryEntry{{0, 0, 0, 1}, {1, 3, 2, 5}, {3, 3, 5, 6}, {6, 6, 9, 10}},
+		},
+		{
+			edits:    []Edit{{Start: 2, End: 5, New: nil}},
+			input:    "abcdef",
+			expected: "abf",
+			history:  []HistoryEntry{{2, 5, 2, 2}},
+		},
+		{
+			edits: []Edit{{Start: 2, End: 7, New: nil}},
+			input: "abcdef",
+			err:   ErrEditOutOfRange,
+		},
+		{
+			edits: []Edit{{Start: 7, End: 7, New: []byte("x")}},
+			input: "abcdef",
+			err:   ErrEditOutOfRange,
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {1, 1}, {2, 3}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				sink := HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				})
+				e, err := NewEditor(c.edits, sink)
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				got, err := transformChunks(e, c.input, size[0], size[1])
+				if err != c.err {
+					t.Fatalf("the error is expected %v but %v", c.err, err)
+				}
+				if err != nil {
+					return
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if fmt.Sprint(history) != fmt.Sprint(c.history) {
+					t.Errorf("the histories are expected %v but %v", c.history, history)
+				}
+			})
+		}
+	}
+}
+
+func TestNewEditor(t *testing.T) {
+	cases := []struct {
+		edits  []Edit
+		hasErr bool
+	}{
+		{edits: []Edit{{Start: 0, End: 2}, {Start: 2, End: 4}}},
+		{edits: []Edit{{Start: 2, End: 2}, {Start: 2, End: 4}, {Start: 2, End: 2}}},
+		{edits: []Edit{{Start: 0, End: 3}, {Start: 2, End: 4}}, hasErr: true},
+		{edits: []Edit{{Start: 0, End: 3}, {Start: 1, End: 1}}, hasErr: true},
+		{edits: []Edit{{Start: 3, End: 2}}, hasErr: true},
+		{edits: []Edit{{Start: -1, End: 2}}, hasErr: true},
+	}
+
+	for i, c := range cases {
+		_, err := NewEditor(c.edits, nil)
+		switch {
+		case c.hasErr && err == nil:
+			t.Errorf("cases[%d] must occur an error but not occured", i)
+		case !c.hasErr && err != nil:
+			t.Errorf("cases[%d] must not occur an error but error occured: %v", i, err)
+		}
+	}
+}
+
+func TestEditsFromHistory(t *testing.T) {
+	const input = "abcxabc"
+	history := NewReplaceHistory()
+	dst, _, err := transform.String(NewReplacer([]byte("abc"), []byte("ABCD"), history), input)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	e, err := NewEditor(EditsFromHistory(history, []byte(dst)), nil)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	got, _, err := transform.String(e, input)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if got != dst {
+		t.Errorf("the output is expected %q but %q", dst, got)
+	}
+}
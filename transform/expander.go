@@ -0,0 +1,228 @@
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"golang.org/x/text/transform"
+)
+
+// ErrMissingKey is returned by Expander when a placeholder is not defined
+// and the policy is MissingKeyError.
+var ErrMissingKey = errors.New("transform: placeholder is not defined")
+
+// MissingKey represents a policy for placeholders which are not defined.
+type MissingKey int
+
+const (
+	// MissingKeyLeave leaves placeholders which are not defined as they are.
+	MissingKeyLeave MissingKey = iota
+	// MissingKeyEmpty replaces placeholders which are not defined to empty.
+	MissingKeyEmpty
+	// MissingKeyError returns ErrMissingKey for placeholders which are not defined.
+	MissingKeyError
+)
+
+// DefaultMaxPlaceholderLen is the default maximum length of a placeholder.
+const DefaultMaxPlaceholderLen = 256
+
+// ExpandOptions represents options of Expander.
+type ExpandOptions struct {
+	// Missing is a policy for placeholders which are not defined.
+	Missing MissingKey
+	// Escape is a prefix of the left delimiter which makes the delimiter literal,
+	// such as "\\" for "\${NAME}" or "$" for "$${NAME}".
+	// The escape and the left delimiter are replaced to the left delimiter.
+	// If Escape is empty, delimiters cannot be escaped.
+	Escape string
+	// MaxLen is the maximum length of a placeholder including its delimiters.
+	// It bounds the bytes which the Expander has to look ahead.
+	// If a placeholder is not closed within MaxLen bytes, it is left as it is.
+	// If MaxLen is 0, DefaultMaxPlaceholderLen is used.
+	// MaxLen is capped at 4096 bytes, the size of the source buffer of
+	// transform.Reader and transform.Writer.
+	MaxLen int
+}
+
+// Expander expands placeholders such as "${NAME}", "{{name}}" or "%name%".
+// It implements transform.Transformer.
+//
+// Spaces around the name of a placeholder are trimmed, so "{{ name }}" is the same as "{{name}}".
+// A placeholder cannot contain a new line.
+type Expander struct {
+	left, right []byte
+	escape      []byte
+	mapping     func(name string) (string, bool)
+	opts        ExpandOptions
+	w           streamWriter
+}
+
+var _ transform.Transformer = (*Expander)(nil)
+
+// NewExpander creates a new Expander which expands placeholders
+// which are enclosed by left and right.
+// mapping returns the value of a placeholder and whether it is defined.
+// If opts is nil, the default options are used.
+//
+// If history is not nil, Expander records histories of expanding.
+func NewExpander(left, right string, mapping func(name string) (string, bool), opts *ExpandOptions, history HistorySink) *Expander {
+	e := &Expander{
+		left:    []byte(left),
+		right:   []byte(right),
+		mapping: mapping,
+		w:       newStreamWriter(history),
+	}
+
+	if opts != nil {
+		e.opts = *opts
+	}
+
+	switch {
+	case e.opts.MaxLen <= 0:
+		e.opts.MaxLen = DefaultMaxPlaceholderLen
+	case e.opts.MaxLen > maxLookahead:
+		e.opts.MaxLen = maxLookahead
+	}
+
+	if min := len(left) + len(right); e.opts.MaxLen < min {
+		e.opts.MaxLen = min
+	}
+
+	if e.opts.Escape != "" {
+		e.escape = []byte(e.opts.Escape + left)
+	}
+
+	return e
+}
+
+// ExpandMap returns a mapping function for NewExpander which looks up m.
+func ExpandMap(m map[string]string) func(name string) (string, bool) {
+	return func(name string) (string, bool) {
+		v, ok := m[name]
+		return v, ok
+	}
+}
+
+// Reset implements transform.Transformer.Reset.
+func (e *Expander) Reset() {
+	e.w.reset()
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When end of src may be a part of a placeholder and atEOF is false,
+// the Expander stops to transform and returns transform.ErrShortSrc.
+// An error which wraps ErrMissingKey is returned
+// if a placeholder is not defined and the policy is MissingKeyError.
+func (e *Expander) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { e.w.done(nDst, nSrc) }()
+
+	nDst, err = e.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	if len(e.left) == 0 || len(e.right) == 0 {
+		return e.w.copy(dst, src, nDst, 0, len(src))
+	}
+
+	for nSrc < len(src) {
+		rest := src[nSrc:]
+
+		if len(e.escape) > 0 {
+			ok, short := matchPrefix(rest, e.escape, atEOF)
+			if short {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			if ok {
+				nDst, err = e.w.replace(dst, nDst, nSrc, nSrc+len(e.escape), e.left)
+				nSrc += len(e.escape)
+				if err != nil {
+					return nDst, nSrc, err
+				}
+				continue
+			}
+		}
+
+		ok, short := matchPrefix(rest, e.left, atEOF)
+		switch {
+		case short:
+			return nDst, nSrc, transform.ErrShortSrc
+		case !ok:
+			// copy until the next candidate of a delimiter
+			n := 1 + bytes.IndexByte(rest[1:], e.left[0])
+			if n == 0 {
+				n = len(rest)
+			}
+			if len(e.escape) > 0 {
+				if i := bytes.IndexByte(rest[1:n], e.escape[0]); i != -1 {
+					n = 1 + i
+				}
+			}
+			if nDst, nSrc, err = e.w.copy(dst, src, nDst, nSrc, nSrc+n); err != nil {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		n, value, short, err := e.expand(rest, atEOF)
+		switch {
+		case err != nil:
+			return nDst, nSrc, err
+		case short:
+			return nDst, nSrc, transform.ErrShortSrc
+		case n == 0:
+			// not a placeholder
+			nDst, nSrc, err = e.w.copy(dst, src, nDst, nSrc, nSrc+len(e.left))
+		default:
+			nDst, err = e.w.replace(dst, nDst, nSrc, nSrc+n, value)
+			nSrc += n
+		}
+
+		if err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	return nDst, nSrc, nil
+}
+
+// expand expands a placeholder at the beginning of src.
+// It returns the length of the placeholder and its value.
+// If src does not begin with a placeholder which should be replaced, n is 0.
+func (e *Expander) expand(src []byte, atEOF bool) (n int, value []byte, short bool, err error) {
+	max := e.opts.MaxLen
+	if max > len(src) {
+		max = len(src)
+	}
+
+	body := src[len(e.left):max]
+	end := bytes.Index(body, e.right)
+	if nl := bytes.IndexByte(body, '\n'); nl != -1 && (end == -1 || nl < end) {
+		return 0, nil, false, nil
+	}
+
+	if end == -1 {
+		if !atEOF && max < e.opts.MaxLen {
+			return 0, nil, true, nil
+		}
+		return 0, nil, false, nil
+	}
+
+	n = len(e.left) + end + len(e.right)
+	name := string(bytes.TrimSpace(body[:end]))
+	v, ok := e.mapping(name)
+	if ok {
+		return n, []byte(v), false, nil
+	}
+
+	switch e.opts.Missing {
+	case MissingKeyEmpty:
+		return n, nil, false, nil
+	case MissingKeyError:
+		return 0, nil, false, fmt.Errorf("%w: %q", ErrMissingKey, name)
+	}
+
+	return 0, nil, false, nil
+}
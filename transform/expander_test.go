@@ -0,0 +1,146 @@
+package transform_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func ExampleExpander() {
+	vars := map[string]string{"NAME": "Gopher"}
+	e := NewExpander("${", "}", ExpandMap(vars), &ExpandOptions{Escape: "$"}, nil)
+	s, _, _ := transform.String(e, "Hello, ${NAME}! $${NAME} is not expanded.")
+	fmt.Println(s)
+	// Output: Hello, Gopher! ${NAME} is not expanded.
+}
+
+func TestExpander_Transform(t *testing.T) {
+	vars := map[string]string{
+		"name":  "Gopher",
+		"empty": "",
+	}
+
+	cases := []struct {
+		left, right string
+		opts        *ExpandOptions
+		input       string
+
+		expected string
+		history  []HistoryEntry
+		err      error
+	}{
+		{
+			left:     "${",
+			right:    "}",
+			input:    "a${name}b${undefined}c${empty}",
+			expected: "aGopherb${undefined}c",
+			history:  []HistoryEntry{{1, 8, 1, 7}, {22, 30, 21, 21}},
+		},
+		{
+			left:     "{{",
+			right:    "}}",
+			opts:     &ExpandOptions{Missing: MissingKeyEmpty},
+			input:    "{{ name }}:{{undefined}}:{{name",
+			expected: "Gopher::{{name",
+			history:  []HistoryEntry{{0, 10, 0, 6}, {11, 24, 7, 7}},
+		},
+		{
+			left:     "%",
+			right:    "%",
+			input:    "100% of %name%",
+			expected: "100% of Gopher",
+			history:  []HistoryEntry{{8, 14, 8, 14}},
+		},
+		{
+			left:     "${",
+			right:    "}",
+			opts:     &ExpandOptions{Escape: `\`},
+			input:    `\${name}${name}\x`,
+			expected: `${name}Gopher\x`,
+			history:  []HistoryEntry{{0, 3, 0, 2}, {8, 15, 7, 13}},
+		},
+		{
+			left:     "${",
+			right:    "}",
+			opts:     &ExpandOptions{MaxLen: 8},
+			input:    "${name}${toolongname}${na\nme}",
+			expected: "Gopher${toolongname}${na\nme}",
+			history:  []HistoryEntry{{0, 7, 0, 6}},
+		},
+		{
+			left:  "${",
+			right: "}",
+			opts:  &ExpandOptions{Missing: MissingKeyError},
+			input: "${name}${undefined}",
+			err:   ErrMissingKey,
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {16, 1}, {20, 4}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				sink := HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				})
+
+				opts := c.opts
+				if opts == nil {
+					opts = &ExpandOptions{}
+				}
+				if opts.MaxLen == 0 {
+					// lookahead must fit into the src buffer
+					opts = &ExpandOptions{Missing: opts.Missing, Escape: opts.Escape, MaxLen: 16}
+				}
+
+				e := NewExpander(c.left, c.right, ExpandMap(vars), opts, sink)
+				got, err := transformChunks(e, c.input, size[0], size[1])
+				if !errors.Is(err, c.err) {
+					t.Fatalf("the error is expected %v but %v", c.err, err)
+				}
+				if err != nil {
+					return
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if fmt.Sprint(history) != fmt.Sprint(c.history) {
+					t.Errorf("the histories are expected %v but %v", c.history, history)
+				}
+			})
+		}
+	}
+}
+
+func TestExpander_Long(t *testing.T) {
+	e := NewExpander("${", "}", ExpandMap(map[string]string{"x": "y"}), nil, nil)
+	input := strings.Repeat("a${x}", 3000)
+	got, _, err := transform.String(e, input)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if expected := strings.Repeat("ay", 3000); got != expected {
+		t.Error("unexpected output")
+	}
+}
+
+func TestExpander_MaxLen(t *testing.T) {
+	e := NewExpander("${", "}", ExpandMap(map[string]string{"x": "y"}), &ExpandOptions{MaxLen: 10000}, nil)
+	input := "${" + strings.Repeat("a", 5000) + " ${x}"
+	got, err := io.ReadAll(transform.NewReader(strings.NewReader(input), e))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if expected := "${" + strings.Repeat("a", 5000) + " y"; string(got) != expected {
+		t.Error("unexpected output")
+	}
+}
@@ -0,0 +1,150 @@
+//go:build ignore
+
+// gen_confusables generates the table of confusables from confusables.txt of UTS #39.
+//
+//	go run gen_confusables.go -output confusables_table.go
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+var (
+	flagURL    = flag.String("url", "https://www.unicode.org/Public/security/latest/confusables.txt", "URL of confusables.txt")
+	flagFile   = flag.String("file", "", "local path of confusables.txt, which is used instead of -url")
+	flagOutput = flag.String("output", "confusables_table.go", "output file")
+)
+
+func main() {
+	flag.Parse()
+
+	r, err := open()
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer r.Close()
+
+	table, version, err := parse(r)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	src, err := generate(table, version)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(*flagOutput, src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func open() (io.ReadCloser, error) {
+	if *flagFile != "" {
+		return os.Open(*flagFile)
+	}
+
+	resp, err := http.Get(*flagURL)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %s", *flagURL, resp.Status)
+	}
+
+	return resp.Body, nil
+}
+
+// parse parses lines such as "0430 ;	0061 ;	MA	# ( а → a ) CYRILLIC SMALL LETTER A → LATIN SMALL LETTER A".
+func parse(r io.Reader) (map[rune]string, string, error) {
+	table := map[rune]string{}
+	var version string
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimPrefix(s.Text(), "\uFEFF")
+		if strings.HasPrefix(line, "# Version:") {
+			version = strings.TrimSpace(strings.TrimPrefix(line, "# Version:"))
+		}
+
+		if i := strings.IndexByte(line, '#'); i != -1 {
+			line = line[:i]
+		}
+
+		fields := strings.Split(line, ";")
+		if len(fields) < 2 {
+			continue
+		}
+
+		src, err := parseRunes(fields[0])
+		if err != nil {
+			return nil, "", err
+		}
+		if len(src) != 1 {
+			return nil, "", fmt.Errorf("unexpected source %q", fields[0])
+		}
+
+		target, err := parseRunes(fields[1])
+		if err != nil {
+			return nil, "", err
+		}
+
+		table[src[0]] = norm.NFD.String(string(target))
+	}
+
+	if err := s.Err(); err != nil {
+		return nil, "", err
+	}
+
+	return table, version, nil
+}
+
+func parseRunes(s string) ([]rune, error) {
+	var rs []rune
+	for _, f := range strings.Fields(s) {
+		r, err := strconv.ParseUint(f, 16, 32)
+		if err != nil {
+			return nil, err
+		}
+		rs = append(rs, rune(r))
+	}
+	return rs, nil
+}
+
+func generate(table map[rune]string, version string) ([]byte, error) {
+	rs := make([]rune, 0, len(table))
+	for r := range table {
+		rs = append(rs, r)
+	}
+	sort.Slice(rs, func(i, j int) bool { return rs[i] < rs[j] })
+
+	var buf bytes.Buffer
+	fmt.Fprintln(&buf, "// Code generated by gen_confusables.go; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintln(&buf, "package transform")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "// confusables maps confusable runes to their prototypes in NFD.\n")
+	fmt.Fprintf(&buf, "// It is generated from confusables.txt (version %s).\n", version)
+	fmt.Fprintln(&buf, "var confusables = map[rune]string{")
+	for _, r := range rs {
+		fmt.Fprintf(&buf, "\t0x%04X: %+q,\n", r, table[r])
+	}
+	fmt.Fprintln(&buf, "}")
+
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,261 @@
+package transform
+
+import (
+	"bytes"
+	"go/scanner"
+	"go/token"
+
+	"golang.org/x/text/transform"
+)
+
+// GoTokenKind represents kinds of tokens of Go source code which GoReplacer replaces.
+// Kinds can be combined with bitwise OR.
+type GoTokenKind uint
+
+const (
+	// GoComment represents comments.
+	// The text of a comment except "//", "/*" and "*/" is replaced.
+	GoComment GoTokenKind = 1 << iota
+	// GoString represents string literals except import paths.
+	// The text of a string literal except its quotes is replaced.
+	GoString
+	// GoIdent represents identifiers.
+	// An identifier is replaced only if the whole identifier matches.
+	GoIdent
+	// GoImportPath represents import paths.
+	// An import path is replaced if it equals old or it begins with old followed by "/",
+	// so that a module path and its package paths are replaced together.
+	GoImportPath
+)
+
+// GoReplacement represents a replacement in Go source code.
+type GoReplacement struct {
+	Kind GoTokenKind
+	// Pos and End are the range of the replaced text in the original source code.
+	Pos, End token.Position
+	Old, New string
+}
+
+// ReplaceGoSource replaces text in the tokens of given kinds in Go source code
+// by the replacing rules which are indicated by ReplaceTable.
+// Other bytes are left byte-identical.
+//
+// The rules are applied in one pass: at each position the first rule in the table
+// which matches is applied, and the replaced text is not replaced by other rules again.
+// ReplaceGoSource returns the replaced source code and the replacements.
+// filename is used only for positions of the replacements.
+func ReplaceGoSource(filename string, src []byte, t ReplaceTable, kinds GoTokenKind) ([]byte, []GoReplacement, error) {
+	rules, ok := t.(*ReplaceRules)
+	if !ok {
+		rules = CompileReplaceTable(t)
+	}
+
+	fset := token.NewFileSet()
+	file := fset.AddFile(filename, -1, len(src))
+
+	var (
+		errs                    scanner.ErrorList
+		s                       scanner.Scanner
+		rs                      []GoReplacement
+		dst                     []byte
+		last                    int // end of the last replacement in src
+		inImport, inImportBlock bool
+	)
+
+	s.Init(file, src, errs.Add, scanner.ScanComments)
+
+	replace := func(kind GoTokenKind, start, end, rule int) {
+		rs = append(rs, GoReplacement{
+			Kind: kind,
+			Pos:  file.Position(file.Pos(start)),
+			End:  file.Position(file.Pos(end)),
+			Old:  string(src[start:end]),
+			New:  string(rules.new[rule]),
+		})
+		dst = append(dst, src[last:start]...)
+		dst = append(dst, rules.new[rule]...)
+		last = end
+	}
+
+	replaceIn := func(kind GoTokenKind, start, end int) {
+		rules.replaceAll(src[start:end], func(_start, _end, rule int) {
+			replace(kind, start+_start, start+_end, rule)
+		})
+	}
+
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		off := file.Offset(pos)
+		switch tok {
+		case token.IMPORT:
+			inImport = true
+		case token.LPAREN:
+			if inImport {
+				inImportBlock = true
+			}
+		case token.RPAREN:
+			inImport, inImportBlock = false, false
+		case token.SEMICOLON:
+			if !inImportBlock {
+				inImport = false
+			}
+		case token.COMMENT:
+			if kinds&GoComment == 0 {
+				break
+			}
+			if lit[1] == '/' {
+				end := bytes.IndexByte(src[off:], '\n')
+				if end == -1 {
+					end = len(src) - off
+				}
+				replaceIn(GoComment, off+2, off+end)
+			} else {
+				end := bytes.Index(src[off+2:], []byte("*/"))
+				if end == -1 {
+					// unterminated comment, the error is reported by the scanner
+					break
+				}
+				replaceIn(GoComment, off+2, off+2+end)
+			}
+		case token.STRING:
+			end := off + len(lit)
+			if src[off] == '`' {
+				// lit of raw string literals does not have carriage returns
+				i := bytes.IndexByte(src[off+1:], '`')
+				if i == -1 {
+					// unterminated raw string literal, the error is reported by the scanner
+					break
+				}
+				end = off + 2 + i
+			}
+
+			switch {
+			case inImport && kinds&GoImportPath != 0:
+				replaceImportPath(rules, src[off+1:end-1], func(n, rule int) {
+					replace(GoImportPath, off+1, off+1+n, rule)
+				})
+			case !inImport && kinds&GoString != 0:
+				replaceIn(GoString, off+1, end-1)
+			}
+		case token.IDENT:
+			if kinds&GoIdent == 0 {
+				break
+			}
+			for i, old := range rules.old {
+				if len(old) != 0 && string(old) == lit {
+					replace(GoIdent, off, off+len(lit), i)
+					break
+				}
+			}
+		}
+	}
+
+	if errs.Len() > 0 {
+		errs.Sort()
+		return nil, nil, errs.Err()
+	}
+
+	dst = append(dst, src[last:]...)
+	return dst, rs, nil
+}
+
+// replaceImportPath calls f with the length of the matched prefix of path and the index of the rule
+// if a rule matches with path.
+func replaceImportPath(rules *ReplaceRules, path []byte, f func(n, rule int)) {
+	for i, old := range rules.old {
+		if len(old) == 0 || !bytes.HasPrefix(path, old) {
+			continue
+		}
+
+		if len(path) == len(old) || path[len(old)] == '/' {
+			f(len(old), i)
+			return
+		}
+	}
+}
+
+// GoReplacer replaces text in the tokens of given kinds in Go source code.
+// It implements transform.Transformer.
+//
+// Because Go source code cannot be tokenized partially,
+// GoReplacer keeps the whole source code until atEOF is true.
+// See ReplaceGoSource for details of replacing.
+type GoReplacer struct {
+	filename     string
+	rules        *ReplaceRules
+	kinds        GoTokenKind
+	w            streamWriter
+	src          []byte
+	replaced     bool
+	replacements []GoReplacement
+}
+
+var _ transform.Transformer = (*GoReplacer)(nil)
+
+// NewGoReplacer creates a new GoReplacer.
+// filename is used only for positions of the replacements.
+//
+// If history is not nil, GoReplacer records histories of replacing.
+func NewGoReplacer(filename string, t ReplaceTable, kinds GoTokenKind, history HistorySink) *GoReplacer {
+	return &GoReplacer{
+		filename: filename,
+		rules:    CompileReplaceTable(t),
+		kinds:    kinds,
+		w:        newStreamWriter(history),
+	}
+}
+
+// Replacements returns the replacements which have been done.
+// The positions of the replacements are on the original source code.
+func (r *GoReplacer) Replacements() []GoReplacement {
+	return r.replacements
+}
+
+// Reset implements transform.Transformer.Reset.
+func (r *GoReplacer) Reset() {
+	r.w.reset()
+	r.src = nil
+	r.replaced = false
+	r.replacements = nil
+}
+
+// Transform implements transform.Transformer.Transform.
+// Transform keeps src until atEOF is true and then replaces the whole source code.
+// If the source code has a syntax error which go/scanner reports,
+// Transform returns the error.
+func (r *GoReplacer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { r.w.done(nDst, nSrc) }()
+
+	nDst, err = r.w.flush(dst)
+	if err != nil || r.replaced {
+		return nDst, 0, err
+	}
+
+	r.src = append(r.src, src...)
+	if !atEOF {
+		return nDst, len(src), nil
+	}
+
+	out, rs, err := ReplaceGoSource(r.filename, r.src, r.rules, r.kinds)
+	if err != nil {
+		return nDst, len(src), err
+	}
+	r.replaced = true
+	r.replacements = rs
+
+	var diff int // len(dst) - len(src) until the current replacement
+	for _, rep := range rs {
+		dst0 := rep.Pos.Offset + diff
+		dst1 := dst0 + len(rep.New)
+		addHistory(r.w.history, rep.Pos.Offset, rep.End.Offset, dst0, dst1)
+		diff += len(rep.New) - (rep.End.Offset - rep.Pos.Offset)
+	}
+	r.src = nil
+
+	nDst, err = r.w.write(dst, nDst, out)
+	return nDst, len(src), err
+}
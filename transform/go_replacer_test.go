@@ -0,0 +1,131 @@
+package transform_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+const goReplacerSrc = `package main
+
+import (
+	"example.com/old"
+	x "example.com/old/sub"
+	"example.com/older"
+)
+
+// old is an old value.
+var old = "old" + ` + "`old`" + `
+
+/* oldest */
+func oldFunc() { println(old, x.V) }
+`
+
+func ExampleReplaceGoSource() {
+	src := []byte("package p\n\nimport \"example.com/old/pkg\"\n\n// use old module\nvar _ = pkg.V\n")
+	t := ReplaceStringTable{"example.com/old", "example.com/new"}
+	dst, rs, err := ReplaceGoSource("p.go", src, t, GoImportPath)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(string(dst))
+	for _, r := range rs {
+		fmt.Println(r.Pos, r.Old, "->", r.New)
+	}
+	// Output:
+	// package p
+	//
+	// import "example.com/new/pkg"
+	//
+	// // use old module
+	// var _ = pkg.V
+	// p.go:3:9 example.com/old -> example.com/new
+}
+
+func TestReplaceGoSource(t *testing.T) {
+	cases := []struct {
+		table    ReplaceStringTable
+		kinds    GoTokenKind
+		expected string
+	}{
+		{
+			table:    ReplaceStringTable{"example.com/old", "example.com/new"},
+			kinds:    GoImportPath,
+			expected: strings.NewReplacer(`"example.com/old"`, `"example.com/new"`, `"example.com/old/sub"`, `"example.com/new/sub"`).Replace(goReplacerSrc),
+		},
+		{
+			table:    ReplaceStringTable{"old", "new"},
+			kinds:    GoIdent,
+			expected: strings.NewReplacer("var old", "var new", "println(old", "println(new").Replace(goReplacerSrc),
+		},
+		{
+			table:    ReplaceStringTable{"old", "new"},
+			kinds:    GoString,
+			expected: strings.NewReplacer(`"old" + `+"`old`", `"new" + `+"`new`").Replace(goReplacerSrc),
+		},
+		{
+			table:    ReplaceStringTable{"old", "new"},
+			kinds:    GoComment,
+			expected: strings.NewReplacer("// old is an old value.", "// new is an new value.", "/* oldest */", "/* newest */").Replace(goReplacerSrc),
+		},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			history := NewReplaceHistory()
+			r := NewGoReplacer("main.go", c.table, c.kinds, history)
+			got, err := transformChunks(r, goReplacerSrc, 10, 7)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+
+			if got != c.expected {
+				t.Errorf("the output is expected\n%s\nbut\n%s", c.expected, got)
+			}
+
+			rs := r.Replacements()
+			if len(rs) != history.Len() {
+				t.Fatalf("the number of replacements is %d but the number of histories is %d", len(rs), history.Len())
+			}
+
+			for j, rep := range rs {
+				src0, src1, dst0, dst1 := history.At(j)
+				if goReplacerSrc[src0:src1] != rep.Old || got[dst0:dst1] != rep.New {
+					t.Errorf("history[%d] is expected %q -> %q but %q -> %q", j, rep.Old, rep.New, goReplacerSrc[src0:src1], got[dst0:dst1])
+				}
+
+				if rep.Pos.Offset != src0 || rep.End.Offset != src1 || rep.Pos.Filename != "main.go" {
+					t.Errorf("the position of replacements[%d] is unexpected %v", j, rep.Pos)
+				}
+			}
+		})
+	}
+}
+
+func TestReplaceGoSource_Error(t *testing.T) {
+	cases := []struct {
+		src   string
+		kinds GoTokenKind
+	}{
+		{"package p\nvar _ = \"a\n", GoString},
+		{"package p\n/* old", GoComment},
+		{"package p\nvar _ = `old", GoString},
+	}
+
+	for _, c := range cases {
+		_, _, err := ReplaceGoSource("a.go", []byte(c.src), ReplaceStringTable{"old", "new"}, c.kinds)
+		if err == nil {
+			t.Errorf("ReplaceGoSource(%q): an error is expected but not occured", c.src)
+		}
+
+		_, _, err = transform.String(NewGoReplacer("a.go", ReplaceStringTable{"old", "new"}, c.kinds, nil), c.src)
+		if err == nil {
+			t.Errorf("GoReplacer(%q): an error is expected but not occured", c.src)
+		}
+	}
+}
@@ -0,0 +1,232 @@
+package transform
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+// HistorySink receives histories of replacing as they happen.
+// ReplaceHistory, RingReplaceHistory, HistoryFunc, HistoryChan and HistoryWriter implement HistorySink.
+//
+// Transformers do not record histories if their sink is nil.
+// A nil pointer or a nil func of the sinks of this package is also treated as nil,
+// but a nil value of other implementations must be passed as an untyped nil.
+type HistorySink interface {
+	// AddHistory is called when src[src0:src1] is replaced to dst[dst0:dst1].
+	AddHistory(src0, src1, dst0, dst1 int)
+}
+
+var (
+	_ HistorySink = (*ReplaceHistory)(nil)
+	_ HistorySink = (*RingReplaceHistory)(nil)
+	_ HistorySink = HistoryFunc(nil)
+	_ HistorySink = HistoryChan(nil)
+	_ HistorySink = (*HistoryWriter)(nil)
+
+	_ LabeledHistorySink = (*ReplaceHistory)(nil)
+	_ LabeledHistorySink = LabeledHistoryFunc(nil)
+)
+
+// LabeledHistorySink is a HistorySink which also receives labels of histories,
+// such as names of detectors which found the replaced data.
+// Transformers which label their histories call AddLabeledHistory instead of AddHistory
+// if the sink implements LabeledHistorySink.
+type LabeledHistorySink interface {
+	HistorySink
+	// AddLabeledHistory is called when src[src0:src1] is replaced to dst[dst0:dst1] by label.
+	AddLabeledHistory(label string, src0, src1, dst0, dst1 int)
+}
+
+// historySink normalizes a typed nil sink of this package,
+// such as a nil *ReplaceHistory or a nil HistoryFunc, to a nil HistorySink.
+// Other implementations of HistorySink must be passed as an untyped nil.
+func historySink(sink HistorySink) HistorySink {
+	var isNil bool
+	switch sink := sink.(type) {
+	case *ReplaceHistory:
+		isNil = sink == nil
+	case *RingReplaceHistory:
+		isNil = sink == nil
+	case *HistoryWriter:
+		isNil = sink == nil
+	case HistoryFunc:
+		isNil = sink == nil
+	case LabeledHistoryFunc:
+		isNil = sink == nil
+	case HistoryChan:
+		isNil = sink == nil
+	}
+
+	if isNil {
+		return nil
+	}
+	return sink
+}
+
+// addHistory records a history into the sink if the sink is not nil.
+// The sink must be normalized by historySink.
+func addHistory(sink HistorySink, src0, src1, dst0, dst1 int) {
+	if sink != nil {
+		sink.AddHistory(src0, src1, dst0, dst1)
+	}
+}
+
+// addLabeledHistory records a labeled history into the sink if the sink is not nil.
+// Like addHistory, the sink must be normalized by historySink.
+// If the sink does not implement LabeledHistorySink, the label is dropped.
+func addLabeledHistory(sink HistorySink, label string, src0, src1, dst0, dst1 int) {
+	switch sink := sink.(type) {
+	case nil:
+	case LabeledHistorySink:
+		sink.AddLabeledHistory(label, src0, src1, dst0, dst1)
+	default:
+		sink.AddHistory(src0, src1, dst0, dst1)
+	}
+}
+
+// HistoryEntry represents a history of replacing,
+// from src[Src0:Src1] to dst[Dst0:Dst1].
+type HistoryEntry struct {
+	Src0, Src1 int
+	Dst0, Dst1 int
+}
+
+// HistoryFunc is a function which implements HistorySink.
+type HistoryFunc func(src0, src1, dst0, dst1 int)
+
+// AddHistory implements HistorySink.AddHistory.
+func (f HistoryFunc) AddHistory(src0, src1, dst0, dst1 int) {
+	f(src0, src1, dst0, dst1)
+}
+
+// LabeledHistoryFunc is a function which implements LabeledHistorySink.
+type LabeledHistoryFunc func(label string, src0, src1, dst0, dst1 int)
+
+// AddHistory implements HistorySink.AddHistory.
+// It calls f with an empty label.
+func (f LabeledHistoryFunc) AddHistory(src0, src1, dst0, dst1 int) {
+	f("", src0, src1, dst0, dst1)
+}
+
+// AddLabeledHistory implements LabeledHistorySink.AddLabeledHistory.
+func (f LabeledHistoryFunc) AddLabeledHistory(label string, src0, src1, dst0, dst1 int) {
+	f(label, src0, src1, dst0, dst1)
+}
+
+// HistoryChan is a channel which implements HistorySink.
+// AddHistory sends a HistoryEntry to the channel, so it blocks until the entry is received.
+type HistoryChan chan<- HistoryEntry
+
+// AddHistory implements HistorySink.AddHistory.
+func (c HistoryChan) AddHistory(src0, src1, dst0, dst1 int) {
+	c <- HistoryEntry{Src0: src0, Src1: src1, Dst0: dst0, Dst1: dst1}
+}
+
+// HistoryWriter is a HistorySink which writes histories to an io.Writer.
+// Each history is written as a line such as "src0 src1 dst0 dst1\n".
+type HistoryWriter struct {
+	w   io.Writer
+	err error
+}
+
+// NewHistoryWriter creates a new HistoryWriter which writes histories to w.
+func NewHistoryWriter(w io.Writer) *HistoryWriter {
+	return &HistoryWriter{w: w}
+}
+
+// AddHistory implements HistorySink.AddHistory.
+// After an error occurred, AddHistory does nothing.
+func (w *HistoryWriter) AddHistory(src0, src1, dst0, dst1 int) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintf(w.w, "%d %d %d %d\n", src0, src1, dst0, dst1)
+}
+
+// Err returns the first error which occurred while writing histories.
+func (w *HistoryWriter) Err() error {
+	return w.err
+}
+
+// RingReplaceHistory is a HistorySink which retains only the last N histories.
+// It is useful for continuous streams such as log tailing,
+// because ReplaceHistory grows without bound.
+// It is safe for concurrent use by multiple goroutines.
+type RingReplaceHistory struct {
+	mu      sync.Mutex
+	entries []HistoryEntry
+	start   int // index of the oldest entry
+	n       int // number of retained entries
+	total   int // number of added entries
+}
+
+// NewRingReplaceHistory creates a new RingReplaceHistory which retains the last n histories.
+// It panics if n is not positive.
+func NewRingReplaceHistory(n int) *RingReplaceHistory {
+	if n <= 0 {
+		panic("transform: the size of RingReplaceHistory must be positive")
+	}
+	return &RingReplaceHistory{entries: make([]HistoryEntry, n)}
+}
+
+// AddHistory implements HistorySink.AddHistory.
+// If the RingReplaceHistory is full, the oldest history is discarded.
+func (h *RingReplaceHistory) AddHistory(src0, src1, dst0, dst1 int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	e := HistoryEntry{Src0: src0, Src1: src1, Dst0: dst0, Dst1: dst1}
+	h.total++
+	if h.n < len(h.entries) {
+		h.entries[(h.start+h.n)%len(h.entries)] = e
+		h.n++
+		return
+	}
+
+	h.entries[h.start] = e
+	h.start = (h.start + 1) % len(h.entries)
+}
+
+// Iterate iterates retained histories from the oldest.
+// if f returns false Iterate will stop the iteration.
+// f must not record histories into h.
+func (h *RingReplaceHistory) Iterate(f func(src0, src1, dst0, dst1 int) bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for i := 0; i < h.n; i++ {
+		e := h.entries[(h.start+i)%len(h.entries)]
+		if !f(e.Src0, e.Src1, e.Dst0, e.Dst1) {
+			break
+		}
+	}
+}
+
+// At returns a retained history of given index.
+// The index 0 is the oldest retained history.
+func (h *RingReplaceHistory) At(index int) (src0, src1, dst0, dst1 int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if index < 0 || index >= h.n {
+		panic(fmt.Sprintf("transform: index out of range [%d] with length %d", index, h.n))
+	}
+
+	e := h.entries[(h.start+index)%len(h.entries)]
+	return e.Src0, e.Src1, e.Dst0, e.Dst1
+}
+
+// Len returns the number of retained histories.
+func (h *RingReplaceHistory) Len() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.n
+}
+
+// Dropped returns the number of discarded histories.
+func (h *RingReplaceHistory) Dropped() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.total - h.n
+}
@@ -0,0 +1,117 @@
+package transform_test
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func ExampleHistoryWriter() {
+	r := NewReplacer([]byte("a"), []byte("AA"), NewHistoryWriter(os.Stdout))
+	transform.String(r, "abcabc")
+	// Output:
+	// 0 1 0 2
+	// 3 4 4 6
+}
+
+func TestRingReplaceHistory(t *testing.T) {
+	history := NewRingReplaceHistory(3)
+	input := strings.Repeat("xa", 5)
+	got, _, err := transform.String(NewReplacer([]byte("a"), []byte("AA"), history), input)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if got != strings.Repeat("xAA", 5) {
+		t.Errorf("the output is expected %q but %q", strings.Repeat("xAA", 5), got)
+	}
+
+	if history.Len() != 3 || history.Dropped() != 2 {
+		t.Errorf("(Len, Dropped) is expected (3, 2) but (%d, %d)", history.Len(), history.Dropped())
+	}
+
+	var actual []string
+	history.Iterate(func(src0, src1, dst0, dst1 int) bool {
+		actual = append(actual, fmt.Sprint(src0, src1, dst0, dst1))
+		return true
+	})
+
+	expected := []string{"5 6 7 9", "7 8 10 12", "9 10 13 15"}
+	if strings.Join(actual, ",") != strings.Join(expected, ",") {
+		t.Errorf("the histories are expected %v but %v", expected, actual)
+	}
+
+	if src0, _, _, _ := history.At(0); src0 != 5 {
+		t.Errorf("the src0 of the oldest history is expected 5 but %d", src0)
+	}
+}
+
+func TestHistoryChan(t *testing.T) {
+	ch := make(chan HistoryEntry, 10)
+	_, _, err := transform.String(NewReplacer([]byte("a"), nil, HistoryChan(ch)), "xaxa")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	close(ch)
+
+	var actual []HistoryEntry
+	for e := range ch {
+		actual = append(actual, e)
+	}
+
+	expected := []HistoryEntry{{1, 2, 1, 1}, {3, 4, 2, 2}}
+	if fmt.Sprint(actual) != fmt.Sprint(expected) {
+		t.Errorf("the histories are expected %v but %v", expected, actual)
+	}
+}
+
+func TestHistoryFunc(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewHistoryWriter(&buf)
+	var n int
+	sink := HistoryFunc(func(src0, src1, dst0, dst1 int) {
+		n++
+		w.AddHistory(src0, src1, dst0, dst1)
+	})
+
+	_, _, err := transform.String(NewReplacer([]byte("a"), []byte("b"), sink), "aaa")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if n != 3 {
+		t.Errorf("the number of histories is expected 3 but %d", n)
+	}
+
+	if buf.String() != "0 1 0 1\n1 2 1 2\n2 3 2 3\n" || w.Err() != nil {
+		t.Errorf("unexpected output %q (err: %v)", buf.String(), w.Err())
+	}
+}
+
+func TestHistorySink_Nil(t *testing.T) {
+	sinks := []HistorySink{
+		(*ReplaceHistory)(nil),
+		(*RingReplaceHistory)(nil),
+		(*HistoryWriter)(nil),
+		HistoryFunc(nil),
+		HistoryChan(nil),
+		LabeledHistoryFunc(nil),
+	}
+
+	for _, sink := range sinks {
+		got, _, err := transform.String(NewReplacer([]byte("a"), []byte("b"), sink), "abc")
+		if err != nil {
+			t.Fatalf("%T: unexpected error: %v", sink, err)
+		}
+
+		if got != "bbc" {
+			t.Errorf("%T: the output is expected %q but %q", sink, "bbc", got)
+		}
+	}
+}
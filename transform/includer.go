@@ -0,0 +1,358 @@
+package transform
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+
+	"golang.org/x/text/transform"
+)
+
+var (
+	// ErrIncludeCycle is returned by Includer when a file includes itself directly or indirectly.
+	ErrIncludeCycle = errors.New("transform: include cycle")
+	// ErrIncludeDepth is returned by Includer when includes are nested too deeply.
+	ErrIncludeDepth = errors.New("transform: too deeply nested includes")
+)
+
+// Default values of IncludeOptions.
+const (
+	DefaultIncludePrefix   = `#include "`
+	DefaultIncludeSuffix   = `"`
+	DefaultIncludeMaxDepth = 16
+)
+
+// IncludeOptions represents options of Includer.
+type IncludeOptions struct {
+	// Prefix and Suffix enclose a path of an include directive.
+	// If they are empty, DefaultIncludePrefix and DefaultIncludeSuffix are used.
+	Prefix, Suffix string
+	// MaxDepth is the maximum depth of nested includes.
+	// If MaxDepth is 0, DefaultIncludeMaxDepth is used.
+	MaxDepth int
+	// MaxPathLen is the maximum length of a path of an include directive.
+	// It bounds the bytes which the Includer has to look ahead.
+	// If MaxPathLen is 0, DefaultMaxPlaceholderLen is used.
+	// Like ExpandOptions.MaxLen, a directive is capped at 4096 bytes.
+	MaxPathLen int
+}
+
+// Include represents a file which is included into the output by Includer.
+type Include struct {
+	// Path is the path of the included file in the fs.FS.
+	Path string
+	// Parent is the index of the Include of the including file.
+	// It is -1 if the file is included by the root stream.
+	Parent int
+	// Src0 and Src1 are the range of the include directive in the including file.
+	Src0, Src1 int
+	// Dst0 and Dst1 are the range of the content of the included file in the output.
+	Dst0, Dst1 int
+}
+
+// Includer expands include directives such as `#include "path"`
+// by streaming the referenced files from an fs.FS.
+// It implements transform.Transformer.
+//
+// Included files are expanded recursively.
+// A path in an included file is relative to the directory of the file,
+// and a path in the root stream is relative to the root of the fs.FS.
+// The directive is replaced by the content of the file; the following new line is kept.
+type Includer struct {
+	fsys   fs.FS
+	opts   IncludeOptions
+	prefix []byte
+	suffix []byte
+	w      streamWriter
+
+	dir      string   // directory of the current file
+	stack    []string // paths of including files
+	includes *[]Include
+	parent   int // index of the Include of the current file
+	base     int // offset of the output of the current file in the root output
+
+	file    fs.File
+	reader  io.Reader
+	child   *Includer // Includer of the file which is being streamed
+	current int       // index of the Include which is being streamed
+}
+
+var (
+	_ transform.Transformer = (*Includer)(nil)
+	_ io.Closer             = (*Includer)(nil)
+)
+
+// NewIncluder creates a new Includer which reads included files from fsys.
+// If opts is nil, the default options are used.
+//
+// If history is not nil, Includer records histories of expanding directives of the root stream.
+// Use Includes and Lookup to know which output ranges came from which file.
+func NewIncluder(fsys fs.FS, opts *IncludeOptions, history HistorySink) *Includer {
+	in := &Includer{
+		fsys:     fsys,
+		w:        newStreamWriter(history),
+		dir:      ".",
+		includes: new([]Include),
+		parent:   -1,
+	}
+
+	if opts != nil {
+		in.opts = *opts
+	}
+
+	if in.opts.Prefix == "" && in.opts.Suffix == "" {
+		in.opts.Prefix, in.opts.Suffix = DefaultIncludePrefix, DefaultIncludeSuffix
+	}
+
+	if in.opts.MaxDepth <= 0 {
+		in.opts.MaxDepth = DefaultIncludeMaxDepth
+	}
+
+	if in.opts.MaxPathLen <= 0 {
+		in.opts.MaxPathLen = DefaultMaxPlaceholderLen
+	}
+
+	in.prefix, in.suffix = []byte(in.opts.Prefix), []byte(in.opts.Suffix)
+
+	if max := maxLookahead - len(in.prefix) - len(in.suffix); in.opts.MaxPathLen > max {
+		in.opts.MaxPathLen = max
+	}
+
+	return in
+}
+
+// Includes returns the included files in the order of including.
+func (in *Includer) Includes() []Include {
+	return *in.includes
+}
+
+// Lookup maps an offset in the output to the file which the byte came from
+// and the offset in the file.
+// path is empty if the byte came from the root stream.
+func (in *Includer) Lookup(dst int) (path string, offset int) {
+	includes := *in.includes
+
+	// the last Include which contains dst is the innermost one,
+	// because an including file is recorded before its included files.
+	current := -1
+	for i, inc := range includes {
+		if inc.Dst0 <= dst && dst < inc.Dst1 {
+			current = i
+		}
+	}
+
+	var base int
+	if current != -1 {
+		path, base = includes[current].Path, includes[current].Dst0
+	}
+
+	offset = dst - base
+	for _, inc := range includes {
+		if inc.Parent == current && inc.Dst1 <= dst {
+			offset -= (inc.Dst1 - inc.Dst0) - (inc.Src1 - inc.Src0)
+		}
+	}
+
+	return path, offset
+}
+
+// Reset implements transform.Transformer.Reset.
+func (in *Includer) Reset() {
+	in.w.reset()
+	in.closeFile()
+	// transform.NewReader resets a child Includer,
+	// so only the root Includer clears the shared records.
+	if in.parent == -1 {
+		*in.includes = nil
+	}
+}
+
+// Close closes the files which are being included, including files which are opened by nested includes.
+// Call Close if the stream is abandoned before its end.
+// Reset also closes them.
+func (in *Includer) Close() error {
+	return in.closeFile()
+}
+
+func (in *Includer) closeFile() error {
+	var err error
+	if in.child != nil {
+		err = in.child.closeFile()
+		in.child = nil
+	}
+
+	in.reader = nil
+	if in.file != nil {
+		if cerr := in.file.Close(); err == nil {
+			err = cerr
+		}
+		in.file = nil
+	}
+
+	return err
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When end of src may be a part of a directive and atEOF is false,
+// the Includer stops to transform and returns transform.ErrShortSrc.
+// Transform returns an error if an included file cannot be read,
+// includes form a cycle (ErrIncludeCycle) or they are nested too deeply (ErrIncludeDepth).
+func (in *Includer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { in.w.done(nDst, nSrc) }()
+
+	nDst, err = in.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	for {
+		if in.reader != nil {
+			if nDst, err = in.read(dst, nDst); err != nil {
+				return nDst, nSrc, err
+			}
+		}
+
+		if nSrc == len(src) {
+			return nDst, nSrc, nil
+		}
+
+		rest := src[nSrc:]
+		ok, short := matchPrefix(rest, in.prefix, atEOF)
+		switch {
+		case short:
+			return nDst, nSrc, transform.ErrShortSrc
+		case !ok:
+			// copy until the next candidate of a directive
+			n := 1 + bytes.IndexByte(rest[1:], in.prefix[0])
+			if n == 0 {
+				n = len(rest)
+			}
+			if nDst, nSrc, err = in.w.copy(dst, src, nDst, nSrc, nSrc+n); err != nil {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		name, n, short := in.directive(rest, atEOF)
+		switch {
+		case short:
+			return nDst, nSrc, transform.ErrShortSrc
+		case n == 0:
+			// not a directive
+			if nDst, nSrc, err = in.w.copy(dst, src, nDst, nSrc, nSrc+len(in.prefix)); err != nil {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		if err := in.open(name, nSrc, nSrc+n, nDst); err != nil {
+			return nDst, nSrc, err
+		}
+		nSrc += n
+	}
+}
+
+// directive parses a directive at the beginning of src
+// and returns the path and the length of the directive.
+// If src does not begin with a directive, n is 0.
+func (in *Includer) directive(src []byte, atEOF bool) (name string, n int, short bool) {
+	body := src[len(in.prefix):]
+	max := in.opts.MaxPathLen + len(in.suffix)
+	if len(body) > max {
+		body = body[:max]
+	}
+
+	end := bytes.Index(body, in.suffix)
+	if nl := bytes.IndexByte(body, '\n'); nl != -1 && (end == -1 || nl < end) {
+		return "", 0, false
+	}
+
+	if end == -1 {
+		return "", 0, !atEOF && len(body) < max
+	}
+
+	if end == 0 {
+		return "", 0, false
+	}
+
+	return string(body[:end]), len(in.prefix) + end + len(in.suffix), false
+}
+
+// open opens an included file and starts streaming it.
+// The range of the directive is src[src0:src1] and the content is written to dst[dst0:].
+func (in *Includer) open(name string, src0, src1, dst0 int) error {
+	p := path.Join(in.dir, name)
+	if !fs.ValidPath(p) {
+		return fmt.Errorf("transform: invalid include path %q", name)
+	}
+
+	for _, s := range in.stack {
+		if s == p {
+			return fmt.Errorf("%w: %q", ErrIncludeCycle, p)
+		}
+	}
+
+	if len(in.stack) >= in.opts.MaxDepth {
+		return fmt.Errorf("%w: %q", ErrIncludeDepth, p)
+	}
+
+	f, err := in.fsys.Open(p)
+	if err != nil {
+		return err
+	}
+
+	*in.includes = append(*in.includes, Include{
+		Path:   p,
+		Parent: in.parent,
+		Src0:   in.w.offSrc + src0,
+		Src1:   in.w.offSrc + src1,
+		Dst0:   in.base + in.w.offDst + dst0,
+	})
+	in.current = len(*in.includes) - 1
+
+	child := &Includer{
+		fsys:     in.fsys,
+		opts:     in.opts,
+		prefix:   in.prefix,
+		suffix:   in.suffix,
+		dir:      path.Dir(p),
+		stack:    append(in.stack[:len(in.stack):len(in.stack)], p),
+		includes: in.includes,
+		parent:   in.current,
+		base:     in.base + in.w.offDst + dst0,
+	}
+
+	in.file = f
+	in.child = child
+	in.reader = transform.NewReader(f, child)
+
+	return nil
+}
+
+// read streams the included file to dst[nDst:].
+func (in *Includer) read(dst []byte, nDst int) (int, error) {
+	for nDst < len(dst) {
+		n, err := in.reader.Read(dst[nDst:])
+		nDst += n
+
+		if err == io.EOF {
+			inc := &(*in.includes)[in.current]
+			inc.Dst1 = in.base + in.w.offDst + nDst
+			if in.parent == -1 {
+				addHistory(in.w.history, inc.Src0, inc.Src1, inc.Dst0, inc.Dst1)
+			}
+			return nDst, in.closeFile()
+		}
+
+		if err != nil {
+			in.closeFile()
+			return nDst, err
+		}
+	}
+
+	return nDst, transform.ErrShortDst
+}
@@ -0,0 +1,224 @@
+package transform_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestIncluder_Transform(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":       {Data: []byte("A\n")},
+		"b.txt":       {Data: []byte("B[#include \"sub/c.txt\"]\n")},
+		"sub/c.txt":   {Data: []byte("C[#include \"d.txt\"]")},
+		"sub/d.txt":   {Data: []byte("D")},
+		"cycle.txt":   {Data: []byte("#include \"cycle2.txt\"")},
+		"cycle2.txt":  {Data: []byte("#include \"cycle.txt\"")},
+		"include.txt": {Data: []byte(`{{a.txt}}`)},
+	}
+
+	cases := []struct {
+		opts  *IncludeOptions
+		input string
+
+		expected string
+		includes []Include
+		err      error
+	}{
+		{
+			input:    "x #include \"a.txt\" y",
+			expected: "x A\n y",
+			includes: []Include{{"a.txt", -1, 2, 18, 2, 4}},
+		},
+		{
+			input:    "#include \"b.txt\"\n#include \"a.txt\"",
+			expected: "B[C[D]]\n\nA\n",
+			includes: []Include{
+				{"b.txt", -1, 0, 16, 0, 8},
+				{"sub/c.txt", 0, 2, 22, 2, 6},
+				{"sub/d.txt", 1, 2, 18, 4, 5},
+				{"a.txt", -1, 17, 33, 9, 11},
+			},
+		},
+		{
+			input:    "#include \"\" #include \"a.txt\n\" #include",
+			expected: "#include \"\" #include \"a.txt\n\" #include",
+		},
+		{
+			opts:     &IncludeOptions{Prefix: "{{", Suffix: "}}"},
+			input:    "{{include.txt}}",
+			expected: "A\n",
+			includes: []Include{
+				{"include.txt", -1, 0, 15, 0, 2},
+				{"a.txt", 0, 0, 9, 0, 2},
+			},
+		},
+		{
+			input: "#include \"cycle.txt\"",
+			err:   ErrIncludeCycle,
+		},
+		{
+			opts:  &IncludeOptions{MaxDepth: 2},
+			input: "#include \"b.txt\"",
+			err:   ErrIncludeDepth,
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {24, 1}, {30, 5}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				in := NewIncluder(fsys, c.opts, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(in, c.input, size[0], size[1])
+				switch {
+				case c.err != nil:
+					if !errors.Is(err, c.err) {
+						t.Fatalf("the error is expected %v but %v", c.err, err)
+					}
+					return
+				case err != nil:
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(in.Includes()), fmt.Sprint(c.includes); g != e {
+					t.Errorf("includes are expected %s but %s", e, g)
+				}
+
+				var n int
+				for _, inc := range c.includes {
+					if inc.Parent == -1 {
+						n++
+					}
+				}
+				if len(history) != n {
+					t.Errorf("the number of histories is expected %d but %d", n, len(history))
+				}
+			})
+		}
+	}
+}
+
+func TestIncluder_Lookup(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.txt":     {Data: []byte("A\n")},
+		"b.txt":     {Data: []byte("B[#include \"sub/c.txt\"]\n")},
+		"sub/c.txt": {Data: []byte("C[#include \"d.txt\"]")},
+		"sub/d.txt": {Data: []byte("D")},
+	}
+
+	input := "#include \"b.txt\"\n#include \"a.txt\"!"
+	in := NewIncluder(fsys, nil, nil)
+	got, err := transformChunks(in, input, 100, 100)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	if got != "B[C[D]]\n\nA\n!" {
+		t.Fatalf("unexpected output %q", got)
+	}
+
+	cases := []struct {
+		dst    int
+		path   string
+		offset int
+	}{
+		{0, "b.txt", 0},
+		{2, "sub/c.txt", 0},
+		{4, "sub/d.txt", 0},
+		{5, "sub/c.txt", 18},
+		{6, "b.txt", 22},
+		{7, "b.txt", 23},
+		{8, "", 16},
+		{9, "a.txt", 0},
+		{11, "", 33},
+	}
+
+	for _, c := range cases {
+		path, offset := in.Lookup(c.dst)
+		if path != c.path || offset != c.offset {
+			t.Errorf("Lookup(%d) is expected (%q, %d) but (%q, %d)", c.dst, c.path, c.offset, path, offset)
+		}
+	}
+}
+
+// openFS is an fs.FS which counts opened files which are not closed.
+type openFS struct {
+	fs.FS
+	n int
+}
+
+func (fsys *openFS) Open(name string) (fs.File, error) {
+	f, err := fsys.FS.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	fsys.n++
+	return &openFile{File: f, fsys: fsys}, nil
+}
+
+type openFile struct {
+	fs.File
+	fsys *openFS
+}
+
+func (f *openFile) Close() error {
+	f.fsys.n--
+	return f.File.Close()
+}
+
+func TestIncluder_Close(t *testing.T) {
+	fsys := &openFS{FS: fstest.MapFS{
+		"b.txt":     {Data: []byte("B[#include \"sub/c.txt\"]\n")},
+		"sub/c.txt": {Data: []byte("C[#include \"d.txt\"]")},
+		"sub/d.txt": {Data: []byte(strings.Repeat("D", 10000))},
+	}}
+
+	in := NewIncluder(fsys, nil, nil)
+	for _, release := range []func() error{
+		func() error { in.Reset(); return nil },
+		in.Close,
+	} {
+		// abandon the stream in the middle of sub/d.txt
+		_, _, err := in.Transform(make([]byte, 10), []byte("#include \"b.txt\""), true)
+		if !errors.Is(err, transform.ErrShortDst) {
+			t.Fatal("unexpected error:", err)
+		}
+		if fsys.n != 3 {
+			t.Fatalf("the number of opened files is expected 3 but %d", fsys.n)
+		}
+
+		if err := release(); err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if fsys.n != 0 {
+			t.Errorf("the number of opened files is expected 0 but %d", fsys.n)
+		}
+	}
+}
+
+func TestIncluder_MaxPathLen(t *testing.T) {
+	in := NewIncluder(fstest.MapFS{}, &IncludeOptions{MaxPathLen: 10000}, nil)
+	input := "#include \"" + strings.Repeat("a", 5000)
+	got, err := io.ReadAll(transform.NewReader(strings.NewReader(input), in))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if string(got) != input {
+		t.Error("unexpected output")
+	}
+}
@@ -0,0 +1,157 @@
+package transform
+
+import (
+	"bytes"
+
+	"golang.org/x/text/transform"
+)
+
+// InsertPosition represents where an Inserter inserts text.
+type InsertPosition int
+
+const (
+	// InsertBefore inserts text before every occurrence of the anchor.
+	InsertBefore InsertPosition = iota
+	// InsertAfter inserts text after every occurrence of the anchor.
+	InsertAfter
+	// InsertAtStart inserts text at the start of the stream.
+	InsertAtStart
+	// InsertAtEnd inserts text at the end of the stream.
+	InsertAtEnd
+)
+
+// Inserter inserts text without consuming any byte data.
+// It implements transform.Transformer.
+//
+// Each insertion is recorded as a history which has a zero-length source range,
+// from src[pos:pos] to dst[dst0:dst1].
+type Inserter struct {
+	anchor, text []byte
+	pos          InsertPosition
+	out          []byte // text+anchor or anchor+text
+	w            streamWriter
+	inserted     bool // text has been inserted at the start or the end
+}
+
+var _ transform.Transformer = (*Inserter)(nil)
+
+// NewInserter creates a new Inserter which inserts text at pos.
+// anchor is used only with InsertBefore and InsertAfter.
+// if anchor is empty with InsertBefore or InsertAfter, the Inserter just copies src to dst.
+//
+// If history is not nil, Inserter records histories of inserting.
+func NewInserter(anchor, text []byte, pos InsertPosition, history HistorySink) *Inserter {
+	var out []byte
+	switch pos {
+	case InsertBefore:
+		out = append(append(out, text...), anchor...)
+	case InsertAfter:
+		out = append(append(out, anchor...), text...)
+	}
+
+	return &Inserter{
+		anchor: anchor,
+		text:   text,
+		pos:    pos,
+		out:    out,
+		w:      newStreamWriter(history),
+	}
+}
+
+// Insert returns an Inserter without history.
+// It is a shorthand for NewInserter(anchor, text, pos, nil).
+func Insert(anchor, text []byte, pos InsertPosition) *Inserter {
+	return NewInserter(anchor, text, pos, nil)
+}
+
+// InsertString returns an Inserter which inserts given string.
+func InsertString(anchor, text string, pos InsertPosition) *Inserter {
+	return Insert([]byte(anchor), []byte(text), pos)
+}
+
+// Reset implements transform.Transformer.Reset.
+func (t *Inserter) Reset() {
+	t.w.reset()
+	t.inserted = false
+}
+
+// Transform implements transform.Transformer.Transform.
+// Transform copies src to dst with inserting text.
+//
+// When end of src matches for part of the anchor and atEOF is false,
+// the Inserter stops to transform and returns transform.ErrShortSrc
+// to receive the matched bytes with next several bytes.
+func (t *Inserter) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { t.w.done(nDst, nSrc) }()
+
+	nDst, err = t.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	switch t.pos {
+	case InsertAtStart:
+		if !t.inserted {
+			t.inserted = true
+			t.w.record(0, 0, nDst, nDst+len(t.text))
+			if nDst, err = t.w.write(dst, nDst, t.text); err != nil {
+				return nDst, 0, err
+			}
+		}
+		return t.w.copy(dst, src, nDst, 0, len(src))
+	case InsertAtEnd:
+		if nDst, nSrc, err = t.w.copy(dst, src, nDst, 0, len(src)); err != nil {
+			return nDst, nSrc, err
+		}
+		if atEOF && !t.inserted {
+			t.inserted = true
+			t.w.record(nSrc, nSrc, nDst, nDst+len(t.text))
+			nDst, err = t.w.write(dst, nDst, t.text)
+		}
+		return nDst, nSrc, err
+	}
+
+	if len(t.anchor) == 0 {
+		return t.w.copy(dst, src, nDst, 0, len(src))
+	}
+
+	for {
+		i := bytes.Index(src[nSrc:], t.anchor)
+
+		if i == -1 { // not found
+			end := len(src)
+			if !atEOF {
+				// exclude bytes which may match the anchor with next several bytes
+				if w := overlapWidth(src[nSrc:], t.anchor); w > 0 {
+					end -= w
+					err = transform.ErrShortSrc
+				}
+			}
+
+			var copyErr error
+			if nDst, nSrc, copyErr = t.w.copy(dst, src, nDst, nSrc, end); copyErr != nil {
+				return nDst, nSrc, copyErr
+			}
+			return nDst, nSrc, err
+		}
+
+		// Copy to the anchor
+		if nDst, nSrc, err = t.w.copy(dst, src, nDst, nSrc, nSrc+i); err != nil {
+			return nDst, nSrc, err
+		}
+
+		switch t.pos {
+		case InsertBefore:
+			t.w.record(nSrc, nSrc, nDst, nDst+len(t.text))
+		case InsertAfter:
+			pos := nSrc + len(t.anchor)
+			t.w.record(pos, pos, nDst+len(t.anchor), nDst+len(t.out))
+		}
+
+		nDst, err = t.w.write(dst, nDst, t.out)
+		nSrc += len(t.anchor)
+		if err != nil {
+			return nDst, nSrc, err
+		}
+	}
+}
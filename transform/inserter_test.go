@@ -0,0 +1,121 @@
+package transform_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func ExampleInserter() {
+	s, _, _ := transform.String(InsertString("func ", "// TODO\n", InsertBefore), "func f() {}\nfunc g() {}\n")
+	fmt.Print(s)
+	// Output:
+	// // TODO
+	// func f() {}
+	// // TODO
+	// func g() {}
+}
+
+func TestInserter_Transform(t *testing.T) {
+	cases := []struct {
+		anchor, text string
+		pos          InsertPosition
+		input        string
+
+		expected string
+		history  []HistoryEntry
+	}{
+		{
+			anchor:   "ab",
+			text:     "<>",
+			pos:      InsertBefore,
+			input:    "xabyabab",
+			expected: "x<>aby<>ab<>ab",
+			history:  []HistoryEntry{{1, 1, 1, 3}, {4, 4, 6, 8}, {6, 6, 10, 12}},
+		},
+		{
+			anchor:   "ab",
+			text:     "<>",
+			pos:      InsertAfter,
+			input:    "xabyaba",
+			expected: "xab<>yab<>a",
+			history:  []HistoryEntry{{3, 3, 3, 5}, {6, 6, 8, 10}},
+		},
+		{
+			text:     "start:",
+			pos:      InsertAtStart,
+			input:    "abc",
+			expected: "start:abc",
+			history:  []HistoryEntry{{0, 0, 0, 6}},
+		},
+		{
+			text:     ":end",
+			pos:      InsertAtEnd,
+			input:    "abc",
+			expected: "abc:end",
+			history:  []HistoryEntry{{3, 3, 3, 7}},
+		},
+		{
+			text:     ":end",
+			pos:      InsertAtEnd,
+			input:    "",
+			expected: ":end",
+			history:  []HistoryEntry{{0, 0, 0, 4}},
+		},
+		{
+			anchor:   "",
+			text:     "<>",
+			pos:      InsertBefore,
+			input:    "abc",
+			expected: "abc",
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {2, 1}, {3, 2}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				sink := HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				})
+				ins := NewInserter([]byte(c.anchor), []byte(c.text), c.pos, sink)
+				got, err := transformChunks(ins, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if fmt.Sprint(history) != fmt.Sprint(c.history) {
+					t.Errorf("the histories are expected %v but %v", c.history, history)
+				}
+
+				for _, h := range history {
+					if got[h.Dst0:h.Dst1] != c.text {
+						t.Errorf("the inserted text is expected %q but %q", c.text, got[h.Dst0:h.Dst1])
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestInserter_Reset(t *testing.T) {
+	ins := InsertString("", "#", InsertAtStart)
+	for i := 0; i < 2; i++ {
+		got, _, err := transform.String(ins, strings.Repeat("a", 3))
+		if err != nil {
+			t.Fatal("unexpected error:", err)
+		}
+		if got != "#aaa" {
+			t.Errorf("the output is expected %q but %q", "#aaa", got)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package transform
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// KanaDirection represents a direction of kana conversion.
+type KanaDirection int
+
+const (
+	// HiraganaToKatakana converts hiragana to katakana, such as "あ" to "ア".
+	HiraganaToKatakana KanaDirection = iota
+	// KatakanaToHiragana converts katakana to hiragana, such as "ア" to "あ".
+	KatakanaToHiragana
+)
+
+// KanaOptions represents options of KanaTransformer.
+type KanaOptions struct {
+	// Approximate converts characters which do not have counterparts to similar ones.
+	// For example, "ヷ" becomes "わ" with a combining voiced sound mark (U+3099),
+	// small katakana such as "ㇰ" become "く" and "ゟ" becomes "ヨリ".
+	// If Approximate is false, such characters are left alone.
+	Approximate bool
+}
+
+// offset between hiragana and katakana
+const kanaOffset = 'ア' - 'あ'
+
+// approximations of characters which do not have counterparts
+var (
+	katakanaApprox = map[rune]string{
+		'ヷ': "わ\u3099",
+		'ヸ': "ゐ\u3099",
+		'ヹ': "ゑ\u3099",
+		'ヺ': "を\u3099",
+		'ヿ': "こと",
+		'ㇰ': "く",
+		'ㇱ': "し",
+		'ㇲ': "す",
+		'ㇳ': "と",
+		'ㇴ': "ぬ",
+		'ㇵ': "は",
+		'ㇶ': "ひ",
+		'ㇷ': "ふ",
+		'ㇸ': "へ",
+		'ㇹ': "ほ",
+		'ㇺ': "む",
+		'ㇻ': "ら",
+		'ㇼ': "り",
+		'ㇽ': "る",
+		'ㇾ': "れ",
+		'ㇿ': "ろ",
+	}
+	hiraganaApprox = map[rune]string{
+		'ゟ': "ヨリ",
+	}
+)
+
+// KanaTransformer converts hiragana to katakana or katakana to hiragana.
+// It implements transform.Transformer.
+//
+// Small kana such as "ぁ" and "ゕ", "ゔ" and the iteration marks "ゝ" and "ゞ"
+// are converted to their counterparts "ァ", "ヵ", "ヴ", "ヽ" and "ヾ", and vice versa.
+// Half-width katakana are not converted; use WidthTransformer to fold them beforehand.
+type KanaTransformer struct {
+	dir  KanaDirection
+	opts KanaOptions
+	w    streamWriter
+}
+
+var _ transform.Transformer = (*KanaTransformer)(nil)
+
+// NewKanaTransformer creates a new KanaTransformer which converts kana in dir.
+// If opts is nil, the default options are used.
+//
+// If history is not nil, KanaTransformer records histories of converted characters.
+func NewKanaTransformer(dir KanaDirection, opts *KanaOptions, history HistorySink) *KanaTransformer {
+	t := &KanaTransformer{
+		dir: dir,
+		w:   newStreamWriter(history),
+	}
+
+	if opts != nil {
+		t.opts = *opts
+	}
+
+	return t
+}
+
+// Reset implements transform.Transformer.Reset.
+func (t *KanaTransformer) Reset() {
+	t.w.reset()
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When src ends with an incomplete rune and atEOF is false,
+// the KanaTransformer stops to transform and returns transform.ErrShortSrc.
+func (t *KanaTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { t.w.done(nDst, nSrc) }()
+
+	nDst, err = t.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	for nSrc < len(src) {
+		if !atEOF && !utf8.FullRune(src[nSrc:]) {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+
+		r, n := utf8.DecodeRune(src[nSrc:])
+		out := t.convert(r)
+		if out == "" {
+			nDst, nSrc, err = t.w.copy(dst, src, nDst, nSrc, nSrc+n)
+		} else {
+			nDst, err = t.w.replace(dst, nDst, nSrc, nSrc+n, []byte(out))
+			nSrc += n
+		}
+
+		if err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	return nDst, nSrc, nil
+}
+
+// convert returns the counterpart of r.
+// If r is not converted, it returns an empty string.
+func (t *KanaTransformer) convert(r rune) string {
+	switch t.dir {
+	case HiraganaToKatakana:
+		// from "ぁ" to "ゖ" and from "ゝ" to "ゞ"
+		if 'ぁ' <= r && r <= 'ゖ' || 'ゝ' <= r && r <= 'ゞ' {
+			return string(r + kanaOffset)
+		}
+		if t.opts.Approximate {
+			return hiraganaApprox[r]
+		}
+	case KatakanaToHiragana:
+		// from "ァ" to "ヶ" and from "ヽ" to "ヾ"
+		if 'ァ' <= r && r <= 'ヶ' || 'ヽ' <= r && r <= 'ヾ' {
+			return string(r - kanaOffset)
+		}
+		if t.opts.Approximate {
+			return katakanaApprox[r]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,70 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestKanaTransformer_Transform(t *testing.T) {
+	cases := []struct {
+		dir   KanaDirection
+		opts  *KanaOptions
+		input string
+
+		expected string
+		history  []HistoryEntry
+	}{
+		{
+			dir:      HiraganaToKatakana,
+			input:    "ゔぁいおりん、ゕゖ、ゝゞ、ゟ、漢字カナ",
+			expected: "ヴァイオリン、ヵヶ、ヽヾ、ゟ、漢字カナ",
+			history:  []HistoryEntry{{0, 3, 0, 3}, {3, 6, 3, 6}, {6, 9, 6, 9}, {9, 12, 9, 12}, {12, 15, 12, 15}, {15, 18, 15, 18}, {21, 24, 21, 24}, {24, 27, 24, 27}, {30, 33, 30, 33}, {33, 36, 33, 36}},
+		},
+		{
+			dir:      HiraganaToKatakana,
+			opts:     &KanaOptions{Approximate: true},
+			input:    "ゟ",
+			expected: "ヨリ",
+			history:  []HistoryEntry{{0, 3, 0, 6}},
+		},
+		{
+			dir:      KatakanaToHiragana,
+			input:    "ヴァイオリン、ヽヾ、ヷヿㇰ、ｶﾅ",
+			expected: "ゔぁいおりん、ゝゞ、ヷヿㇰ、ｶﾅ",
+			history:  []HistoryEntry{{0, 3, 0, 3}, {3, 6, 3, 6}, {6, 9, 6, 9}, {9, 12, 9, 12}, {12, 15, 12, 15}, {15, 18, 15, 18}, {21, 24, 21, 24}, {24, 27, 24, 27}},
+		},
+		{
+			dir:      KatakanaToHiragana,
+			opts:     &KanaOptions{Approximate: true},
+			input:    "ヷヿㇰー",
+			expected: "わ\u3099ことくー",
+			history:  []HistoryEntry{{0, 3, 0, 6}, {3, 6, 6, 12}, {6, 9, 12, 15}},
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {3, 1}, {4, 3}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				k := NewKanaTransformer(c.dir, c.opts, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(k, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
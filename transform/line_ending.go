@@ -0,0 +1,151 @@
+package transform
+
+import (
+	"bytes"
+	"runtime"
+
+	"golang.org/x/text/transform"
+)
+
+// LineEnding represents a style of line endings.
+type LineEnding int
+
+const (
+	// LineEndingLF represents "\n".
+	LineEndingLF LineEnding = iota
+	// LineEndingCRLF represents "\r\n".
+	LineEndingCRLF
+	// LineEndingNative represents the line ending of the running OS,
+	// "\r\n" on Windows and "\n" on others.
+	LineEndingNative
+)
+
+// bytes returns the line ending as byte data.
+func (e LineEnding) bytes() []byte {
+	switch {
+	case e == LineEndingCRLF,
+		e == LineEndingNative && runtime.GOOS == "windows":
+		return []byte("\r\n")
+	}
+	return []byte("\n")
+}
+
+// byte order marks which LineEndingNormalizer strips.
+var boms = [][]byte{
+	{0xEF, 0xBB, 0xBF}, // UTF-8
+	{0xFE, 0xFF},       // UTF-16 (big endian)
+	{0xFF, 0xFE},       // UTF-16 (little endian)
+}
+
+// LineEndingNormalizer converts "\r\n", "\r" and "\n" to a line ending style.
+// It implements transform.Transformer.
+//
+// The LineEndingNormalizer handles line endings as byte data,
+// so UTF-16 input should be decoded before normalizing.
+type LineEndingNormalizer struct {
+	eol      []byte
+	stripBOM bool
+	w        streamWriter
+}
+
+var _ transform.Transformer = (*LineEndingNormalizer)(nil)
+
+// NewLineEndingNormalizer creates a new LineEndingNormalizer which converts line endings to eol.
+// If stripBOM is true, a UTF-8 or UTF-16 byte order mark at the start of the stream is removed.
+//
+// If history is not nil, LineEndingNormalizer records histories of converted line endings
+// and the removed byte order mark. Line endings which are already eol are not recorded.
+func NewLineEndingNormalizer(eol LineEnding, stripBOM bool, history HistorySink) *LineEndingNormalizer {
+	return &LineEndingNormalizer{
+		eol:      eol.bytes(),
+		stripBOM: stripBOM,
+		w:        newStreamWriter(history),
+	}
+}
+
+// ToLF returns a LineEndingNormalizer which converts line endings to "\n" without history.
+func ToLF() *LineEndingNormalizer {
+	return NewLineEndingNormalizer(LineEndingLF, false, nil)
+}
+
+// ToCRLF returns a LineEndingNormalizer which converts line endings to "\r\n" without history.
+func ToCRLF() *LineEndingNormalizer {
+	return NewLineEndingNormalizer(LineEndingCRLF, false, nil)
+}
+
+// ToNative returns a LineEndingNormalizer which converts line endings
+// to the line ending of the running OS without history.
+func ToNative() *LineEndingNormalizer {
+	return NewLineEndingNormalizer(LineEndingNative, false, nil)
+}
+
+// Reset implements transform.Transformer.Reset.
+func (n *LineEndingNormalizer) Reset() {
+	n.w.reset()
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When src ends with "\r" or a part of a byte order mark and atEOF is false,
+// the LineEndingNormalizer stops to transform and returns transform.ErrShortSrc.
+func (n *LineEndingNormalizer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { n.w.done(nDst, nSrc) }()
+
+	nDst, err = n.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	if n.stripBOM && n.w.offSrc == 0 {
+		for _, bom := range boms {
+			ok, short := matchPrefix(src, bom, atEOF)
+			if short {
+				return nDst, 0, transform.ErrShortSrc
+			}
+			if ok {
+				n.w.record(0, len(bom), nDst, nDst)
+				nSrc = len(bom)
+				break
+			}
+		}
+	}
+
+	for nSrc < len(src) {
+		var size int
+		switch src[nSrc] {
+		case '\r':
+			if nSrc+1 == len(src) && !atEOF {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			size = 1
+			if nSrc+1 < len(src) && src[nSrc+1] == '\n' {
+				size = 2
+			}
+		case '\n':
+			size = 1
+		default:
+			// copy until the next line ending
+			end := len(src)
+			if i := bytes.IndexAny(src[nSrc:], "\r\n"); i != -1 {
+				end = nSrc + i
+			}
+			if nDst, nSrc, err = n.w.copy(dst, src, nDst, nSrc, end); err != nil {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		if bytes.Equal(src[nSrc:nSrc+size], n.eol) {
+			nDst, err = n.w.write(dst, nDst, n.eol)
+		} else {
+			nDst, err = n.w.replace(dst, nDst, nSrc, nSrc+size, n.eol)
+		}
+		nSrc += size
+
+		if err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	return nDst, nSrc, nil
+}
@@ -0,0 +1,81 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestLineEndingNormalizer_Transform(t *testing.T) {
+	cases := []struct {
+		eol      LineEnding
+		stripBOM bool
+		input    string
+
+		expected string
+		history  []HistoryEntry
+	}{
+		{
+			eol:      LineEndingLF,
+			input:    "a\r\nb\rc\nd\r",
+			expected: "a\nb\nc\nd\n",
+			history:  []HistoryEntry{{1, 3, 1, 2}, {4, 5, 3, 4}, {8, 9, 7, 8}},
+		},
+		{
+			eol:      LineEndingCRLF,
+			input:    "a\r\nb\rc\nd",
+			expected: "a\r\nb\r\nc\r\nd",
+			history:  []HistoryEntry{{4, 5, 4, 6}, {6, 7, 7, 9}},
+		},
+		{
+			eol:      LineEndingLF,
+			input:    "\r\r\n\n",
+			expected: "\n\n\n",
+			history:  []HistoryEntry{{0, 1, 0, 1}, {1, 3, 1, 2}},
+		},
+		{
+			eol:      LineEndingLF,
+			stripBOM: true,
+			input:    "\xEF\xBB\xBFa\r\n\xEF\xBB\xBF",
+			expected: "a\n\xEF\xBB\xBF",
+			history:  []HistoryEntry{{0, 3, 0, 0}, {4, 6, 1, 2}},
+		},
+		{
+			eol:      LineEndingCRLF,
+			stripBOM: true,
+			input:    "\xFF\xFEa",
+			expected: "a",
+			history:  []HistoryEntry{{0, 2, 0, 0}},
+		},
+		{
+			eol:      LineEndingLF,
+			input:    "\xEF\xBB\xBFa",
+			expected: "\xEF\xBB\xBFa",
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {3, 1}, {4, 3}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				n := NewLineEndingNormalizer(c.eol, c.stripBOM, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(n, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package transform
+
+import (
+	"bytes"
+	"errors"
+
+	"golang.org/x/text/transform"
+)
+
+// ErrLineTooLong is returned by LineTransformer when a line is longer than
+// the maximum length and the policy is LineOverflowError.
+var ErrLineTooLong = errors.New("transform: line too long")
+
+// LineOverflow represents a policy for lines which are longer than the maximum length.
+type LineOverflow int
+
+const (
+	// LineOverflowError returns ErrLineTooLong for a too long line.
+	LineOverflowError LineOverflow = iota
+	// LineOverflowSplit splits a too long line into lines of the maximum length.
+	// The split lines except the last one are passed without a line ending.
+	LineOverflowSplit
+	// LineOverflowCopy copies a too long line as it is without calling the function.
+	LineOverflowCopy
+)
+
+// DefaultMaxLineLen is the default maximum length of a line.
+const DefaultMaxLineLen = 64 * 1024
+
+// LineOptions represents options of LineTransformer.
+type LineOptions struct {
+	// MaxLen is the maximum length of a line excluding its line ending.
+	// If MaxLen is 0, DefaultMaxLineLen is used.
+	MaxLen int
+	// Overflow is a policy for lines which are longer than MaxLen.
+	Overflow LineOverflow
+}
+
+// LineFunc transforms a line.
+// line does not contain its line ending, "\n" or "\r\n".
+// LineFunc returns the new line and whether the line is kept.
+// If keep is false, the line and its line ending are dropped.
+// The line ending of a kept line is preserved.
+//
+// line is valid only during the call, so LineFunc must not retain it.
+type LineFunc func(line []byte) (new []byte, keep bool)
+
+// LineTransformer transforms the input line by line.
+// It implements transform.Transformer.
+//
+// Unlike other transformers, LineTransformer buffers a line until its line ending,
+// so a line can be longer than the source buffer of a Transform call.
+// The last line which does not have a line ending is transformed at EOF.
+type LineTransformer struct {
+	f       LineFunc
+	opts    LineOptions
+	line    []byte // buffered current line
+	src0    int    // start of the current line in the input
+	started bool   // the current line has started
+	copying bool   // the current line is too long and copied as it is
+	w       streamWriter
+}
+
+var _ transform.Transformer = (*LineTransformer)(nil)
+
+// NewLineTransformer creates a new LineTransformer which transforms each line by f.
+// If opts is nil, the default options are used.
+//
+// If history is not nil, LineTransformer records histories of changed and dropped lines.
+// A history of a changed line does not contain its line ending,
+// and a history of a dropped line contains its line ending.
+func NewLineTransformer(f LineFunc, opts *LineOptions, history HistorySink) *LineTransformer {
+	t := &LineTransformer{
+		f: f,
+		w: newStreamWriter(history),
+	}
+
+	if opts != nil {
+		t.opts = *opts
+	}
+
+	if t.opts.MaxLen <= 0 {
+		t.opts.MaxLen = DefaultMaxLineLen
+	}
+
+	return t
+}
+
+// Reset implements transform.Transformer.Reset.
+func (t *LineTransformer) Reset() {
+	t.w.reset()
+	t.line = t.line[:0]
+	t.started = false
+	t.copying = false
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// Transform returns ErrLineTooLong if a line is longer than the maximum length
+// and the policy is LineOverflowError.
+// When a line reaches the maximum length at the end of src with '\r' and atEOF is false,
+// the LineTransformer stops to transform and returns transform.ErrShortSrc
+// because the '\r' may be a part of "\r\n".
+func (t *LineTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { t.w.done(nDst, nSrc) }()
+
+	nDst, err = t.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	for nSrc < len(src) {
+		rest := src[nSrc:]
+		i := bytes.IndexByte(rest, '\n')
+
+		if t.copying {
+			end := len(src)
+			if i != -1 {
+				end = nSrc + i + 1
+			}
+			if nDst, nSrc, err = t.w.copy(dst, src, nDst, nSrc, end); err != nil {
+				return nDst, nSrc, err
+			}
+			t.copying = i == -1
+			continue
+		}
+
+		if !t.started {
+			t.started = true
+			t.src0 = t.w.offSrc + nSrc
+		}
+
+		n := len(rest)
+		if i != -1 {
+			n = i
+		}
+
+		// '\r' of "\r\n" is a part of the line ending
+		m := n
+		if i != -1 && n > 0 && rest[n-1] == '\r' {
+			m--
+		}
+
+		if room := t.opts.MaxLen - len(t.line); m > room {
+			if i == -1 && !atEOF && m == room+1 && rest[m-1] == '\r' {
+				// the '\r' may be a part of "\r\n" with next bytes
+				t.line = append(t.line, rest[:room]...)
+				nSrc += room
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+
+			t.line = append(t.line, rest[:room]...)
+			nSrc += room
+
+			switch t.opts.Overflow {
+			case LineOverflowSplit:
+				nDst, err = t.emit(dst, nDst, t.w.offSrc+nSrc, nil)
+			case LineOverflowCopy:
+				t.copying = true
+				nDst, err = t.w.write(dst, nDst, t.line)
+				t.started = false
+				t.line = t.line[:0]
+			default:
+				return nDst, nSrc, ErrLineTooLong
+			}
+
+			if err != nil {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		t.line = append(t.line, rest[:n]...)
+		nSrc += n
+		if i == -1 {
+			continue
+		}
+
+		nSrc++
+		eol := []byte("\n")
+		if len(t.line) > 0 && t.line[len(t.line)-1] == '\r' {
+			t.line = t.line[:len(t.line)-1]
+			eol = []byte("\r\n")
+		}
+
+		if nDst, err = t.emit(dst, nDst, t.w.offSrc+nSrc, eol); err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	if atEOF && t.started {
+		nDst, err = t.emit(dst, nDst, t.w.offSrc+nSrc, nil)
+	}
+
+	return nDst, nSrc, err
+}
+
+// emit transforms the current line and writes it with eol to dst[nDst:].
+// src1 is the end of the line including eol in the input.
+func (t *LineTransformer) emit(dst []byte, nDst, src1 int, eol []byte) (int, error) {
+	line := t.line
+	t.line = t.line[:0]
+	t.started = false
+
+	dst0 := t.w.offDst + nDst
+	new, keep := t.f(line)
+	switch {
+	case !keep:
+		addHistory(t.w.history, t.src0, src1, dst0, dst0)
+		return nDst, nil
+	case !bytes.Equal(new, line):
+		addHistory(t.w.history, t.src0, t.src0+len(line), dst0, dst0+len(new))
+	}
+
+	out := make([]byte, 0, len(new)+len(eol))
+	out = append(append(out, new...), eol...)
+	return t.w.write(dst, nDst, out)
+}
@@ -0,0 +1,110 @@
+package transform_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestLineTransformer_Transform(t *testing.T) {
+	prefix := func(line []byte) ([]byte, bool) {
+		return append([]byte("> "), line...), true
+	}
+
+	cases := []struct {
+		f     LineFunc
+		opts  *LineOptions
+		input string
+
+		expected string
+		history  []HistoryEntry
+		err      error
+	}{
+		{
+			f:        prefix,
+			input:    "a\nbc\r\n\nd",
+			expected: "> a\n> bc\r\n> \n> d",
+			history:  []HistoryEntry{{0, 1, 0, 3}, {2, 4, 4, 8}, {6, 6, 10, 12}, {7, 8, 13, 16}},
+		},
+		{
+			// drop comment lines and trim others
+			f: func(line []byte) ([]byte, bool) {
+				if bytes.HasPrefix(line, []byte("#")) {
+					return nil, false
+				}
+				return bytes.TrimSpace(line), true
+			},
+			input:    "# comment\n  a  \nb\n#\n",
+			expected: "a\nb\n",
+			history:  []HistoryEntry{{0, 10, 0, 0}, {10, 15, 0, 1}, {18, 20, 4, 4}},
+		},
+		{
+			f:        prefix,
+			opts:     &LineOptions{MaxLen: 3, Overflow: LineOverflowSplit},
+			input:    "abcdefg\nab\n",
+			expected: "> abc> def> g\n> ab\n",
+			history:  []HistoryEntry{{0, 3, 0, 5}, {3, 6, 5, 10}, {6, 7, 10, 13}, {8, 10, 14, 18}},
+		},
+		{
+			f:        prefix,
+			opts:     &LineOptions{MaxLen: 3, Overflow: LineOverflowCopy},
+			input:    "abcdefg\nab\nabcd",
+			expected: "abcdefg\n> ab\nabcd",
+			history:  []HistoryEntry{{8, 10, 8, 12}},
+		},
+		{
+			f:     prefix,
+			opts:  &LineOptions{MaxLen: 3},
+			input: "abc\nabcd\n",
+			err:   ErrLineTooLong,
+		},
+		{
+			// '\r' of "\r\n" is not counted
+			f:        prefix,
+			opts:     &LineOptions{MaxLen: 3},
+			input:    "abc\r\nab\r\n",
+			expected: "> abc\r\n> ab\r\n",
+			history:  []HistoryEntry{{0, 3, 0, 5}, {5, 7, 7, 11}},
+		},
+		{
+			f:        prefix,
+			opts:     &LineOptions{MaxLen: 3, Overflow: LineOverflowSplit},
+			input:    "abc\r\nabc\rd\n",
+			expected: "> abc\r\n> abc> \rd\n",
+			history:  []HistoryEntry{{0, 3, 0, 5}, {5, 8, 7, 12}, {8, 10, 12, 16}},
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {2, 1}, {3, 2}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				lt := NewLineTransformer(c.f, c.opts, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(lt, c.input, size[0], size[1])
+				switch {
+				case c.err != nil:
+					if !errors.Is(err, c.err) {
+						t.Fatalf("the error is expected %v but %v", c.err, err)
+					}
+					return
+				case err != nil:
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package transform
+
+import (
+	"errors"
+
+	"golang.org/x/text/transform"
+)
+
+// ErrUnbalancedMarker is returned by MarkerReplacer when an end marker does not have
+// the corresponding begin marker or a begin marker is not closed at EOF.
+var ErrUnbalancedMarker = errors.New("transform: unbalanced markers")
+
+// MarkerReplacer replaces byte data only in regions between begin and end markers
+// such as "<!-- BEGIN:api -->" and "<!-- END:api -->".
+// It implements transform.Transformer.
+//
+// Regions can be repeated and nested. The markers themselves are not replaced.
+// If begin and end are the same, regions cannot be nested.
+// If begin or end is empty, the MarkerReplacer just copies src to dst.
+type MarkerReplacer struct {
+	begin, end []byte
+	rules      *ReplaceRules
+	content    []byte // begin marker and new content of a region
+	depth      int    // depth of nested regions
+	src0, dst0 int    // start of the content of the current outermost region
+	w          streamWriter
+}
+
+var _ transform.Transformer = (*MarkerReplacer)(nil)
+
+// NewMarkerReplacer creates a new MarkerReplacer which replaces byte data in regions
+// by the replacing rules which are indicated by ReplaceTable.
+// At each position the first rule in the table which matches is applied.
+// A match must not contain a marker.
+//
+// If history is not nil, MarkerReplacer records histories of replacing.
+func NewMarkerReplacer(begin, end []byte, t ReplaceTable, history HistorySink) *MarkerReplacer {
+	return &MarkerReplacer{
+		begin: begin,
+		end:   end,
+		rules: CompileReplaceTable(t),
+		w:     newStreamWriter(history),
+	}
+}
+
+// NewMarkerContentReplacer creates a new MarkerReplacer which replaces
+// the whole content of each region to content.
+// If regions are nested, the content of the outermost region is replaced.
+//
+// If history is not nil, MarkerReplacer records histories of replacing.
+// Each history represents the whole content of a region.
+func NewMarkerContentReplacer(begin, end, content []byte, history HistorySink) *MarkerReplacer {
+	return &MarkerReplacer{
+		begin:   begin,
+		end:     end,
+		content: append(append([]byte(nil), begin...), content...),
+		w:       newStreamWriter(history),
+	}
+}
+
+// Reset implements transform.Transformer.Reset.
+func (r *MarkerReplacer) Reset() {
+	r.w.reset()
+	r.depth = 0
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When end of src matches for part of a marker or a rule and atEOF is false,
+// the MarkerReplacer stops to transform and returns transform.ErrShortSrc.
+// If the markers are unbalanced, Transform returns ErrUnbalancedMarker.
+func (r *MarkerReplacer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { r.w.done(nDst, nSrc) }()
+
+	nDst, err = r.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	if len(r.begin) == 0 || len(r.end) == 0 {
+		return r.w.copy(dst, src, nDst, 0, len(src))
+	}
+
+	if nDst, nSrc, err = r.w.copyRest(dst, src, nDst); err != nil {
+		return nDst, nSrc, err
+	}
+
+	for nSrc < len(src) {
+		isBegin, isEnd, short := r.matchMarkers(src[nSrc:], atEOF)
+		if short {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+
+		switch {
+		case isEnd && r.depth > 0:
+			r.depth--
+			if r.rules != nil {
+				nDst, nSrc, err = r.w.copyToken(dst, src, nDst, nSrc, len(r.end))
+			} else if r.depth == 0 {
+				content := len(r.content) - len(r.begin)
+				addHistory(r.w.history, r.src0, r.w.offSrc+nSrc, r.dst0, r.dst0+content)
+				nDst, nSrc, err = r.w.copyToken(dst, src, nDst, nSrc, len(r.end))
+			} else {
+				nSrc += len(r.end)
+			}
+		case isBegin:
+			r.depth++
+			if r.rules != nil {
+				nDst, nSrc, err = r.w.copyToken(dst, src, nDst, nSrc, len(r.begin))
+			} else if r.depth == 1 {
+				nSrc += len(r.begin)
+				r.src0 = r.w.offSrc + nSrc
+				r.dst0 = r.w.offDst + nDst + len(r.begin)
+				nDst, err = r.w.write(dst, nDst, r.content)
+			} else {
+				nSrc += len(r.begin)
+			}
+		case isEnd:
+			return nDst, nSrc, ErrUnbalancedMarker
+		case r.depth > 0 && r.rules == nil:
+			// skip the content of the region
+			nSrc++
+		case r.depth > 0:
+			rule, short := r.match(src[nSrc:], atEOF)
+			if short {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+
+			if rule == -1 {
+				nDst, nSrc, err = r.w.copy(dst, src, nDst, nSrc, nSrc+1)
+				break
+			}
+
+			old, new := r.rules.At(rule)
+			nDst, err = r.w.replace(dst, nDst, nSrc, nSrc+len(old), new)
+			nSrc += len(old)
+		default:
+			nDst, nSrc, err = r.w.copy(dst, src, nDst, nSrc, nSrc+1)
+		}
+
+		if err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	if atEOF && r.depth > 0 {
+		return nDst, nSrc, ErrUnbalancedMarker
+	}
+
+	return nDst, nSrc, nil
+}
+
+// matchMarkers reports whether src begins with the begin marker or the end marker.
+func (r *MarkerReplacer) matchMarkers(src []byte, atEOF bool) (isBegin, isEnd, short bool) {
+	isBegin, shortBegin := matchPrefix(src, r.begin, atEOF)
+	isEnd, shortEnd := matchPrefix(src, r.end, atEOF)
+	return isBegin, isEnd, shortBegin || shortEnd
+}
+
+// match returns the index of the first rule which matches at the beginning of src
+// without containing markers, or -1.
+func (r *MarkerReplacer) match(src []byte, atEOF bool) (rule int, short bool) {
+	for i, old := range r.rules.old {
+		if len(old) == 0 {
+			continue
+		}
+
+		ok, short := matchPrefix(src, old, atEOF)
+		if short {
+			return -1, true
+		}
+		if !ok {
+			continue
+		}
+
+		hasMarker := false
+		for k := 1; k < len(old); k++ {
+			isBegin, isEnd, short := r.matchMarkers(src[k:], atEOF)
+			if short {
+				return -1, true
+			}
+			if isBegin || isEnd {
+				hasMarker = true
+				break
+			}
+		}
+
+		if !hasMarker {
+			return i, false
+		}
+	}
+
+	return -1, false
+}
@@ -0,0 +1,103 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestMarkerReplacer_Transform(t *testing.T) {
+	const (
+		begin = "<!-- BEGIN -->"
+		end   = "<!-- END -->"
+	)
+
+	cases := []struct {
+		table   ReplaceStringTable
+		content *string
+		input   string
+
+		expected string
+		history  []HistoryEntry
+		err      error
+	}{
+		{
+			table:    ReplaceStringTable{"old", "new"},
+			input:    "old" + begin + "old old" + end + "old" + begin + "old" + end,
+			expected: "old" + begin + "new new" + end + "old" + begin + "new" + end,
+			history:  []HistoryEntry{{17, 20, 17, 20}, {21, 24, 21, 24}, {53, 56, 53, 56}},
+		},
+		{
+			// nested regions
+			table:    ReplaceStringTable{"old", "new"},
+			input:    begin + "old" + begin + "old" + end + "old" + end + "old",
+			expected: begin + "new" + begin + "new" + end + "new" + end + "old",
+			history:  []HistoryEntry{{14, 17, 14, 17}, {31, 34, 31, 34}, {46, 49, 46, 49}},
+		},
+		{
+			content:  new(string),
+			input:    "a" + begin + "b" + begin + "c" + end + "d" + end + "e" + begin + end,
+			expected: "a" + begin + end + "e" + begin + end,
+			history:  []HistoryEntry{{15, 44, 15, 15}, {71, 71, 42, 42}},
+		},
+		{
+			table: ReplaceStringTable{"old", "new"},
+			input: begin + "old",
+			err:   ErrUnbalancedMarker,
+		},
+		{
+			table: ReplaceStringTable{"old", "new"},
+			input: "old" + end,
+			err:   ErrUnbalancedMarker,
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {14, 1}, {20, 7}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				sink := HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				})
+
+				var r *MarkerReplacer
+				if c.content != nil {
+					r = NewMarkerContentReplacer([]byte(begin), []byte(end), []byte(*c.content), sink)
+				} else {
+					r = NewMarkerReplacer([]byte(begin), []byte(end), c.table, sink)
+				}
+
+				got, err := transformChunks(r, c.input, size[0], size[1])
+				if err != c.err {
+					t.Fatalf("the error is expected %v but %v", c.err, err)
+				}
+				if err != nil {
+					return
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if fmt.Sprint(history) != fmt.Sprint(c.history) {
+					t.Errorf("the histories are expected %v but %v", c.history, history)
+				}
+			})
+		}
+	}
+}
+
+func TestMarkerContentReplacer(t *testing.T) {
+	r := NewMarkerContentReplacer([]byte("/* BEGIN */"), []byte("/* END */"), []byte("\ngenerated\n"), nil)
+	got, err := transformChunks(r, "x /* BEGIN */ old /* END */ y", 100, 100)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	const expected = "x /* BEGIN */\ngenerated\n/* END */ y"
+	if got != expected {
+		t.Errorf("the output is expected %q but %q", expected, got)
+	}
+}
@@ -0,0 +1,128 @@
+package transform
+
+import (
+	"bytes"
+
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormReplacer replaces byte data which matches a replacing rule
+// under a Unicode normalization form.
+// It implements transform.Transformer.
+//
+// For example, with norm.NFC, "\u00e9" and "e\u0301" match each other.
+// Byte data which does not match is copied as it is without normalizing.
+// A match begins and ends at boundaries of normalization segments,
+// so "e" does not match the beginning of "e\u0301".
+type NormReplacer struct {
+	form  norm.Form
+	rules *ReplaceRules // old of rules are normalized
+	seg   []byte        // buffer for normalized segments
+	w     streamWriter
+}
+
+var _ transform.Transformer = (*NormReplacer)(nil)
+
+// NewNormReplacer creates a new NormReplacer which replaces byte data
+// by the replacing rules which are indicated by ReplaceTable under form.
+// At each position the first rule in the table which matches is applied.
+//
+// If history is not nil, NormReplacer records histories of replacing,
+// whose source ranges are the matched byte data in the original input.
+func NewNormReplacer(form norm.Form, t ReplaceTable, history HistorySink) *NormReplacer {
+	rules := CompileReplaceTable(t)
+	for i, old := range rules.old {
+		rules.old[i] = form.Bytes(old)
+	}
+
+	return &NormReplacer{
+		form:  form,
+		rules: rules,
+		w:     newStreamWriter(history),
+	}
+}
+
+// Reset implements transform.Transformer.Reset.
+func (r *NormReplacer) Reset() {
+	r.w.reset()
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When end of src may be a part of a match or a normalization segment and atEOF is false,
+// the NormReplacer stops to transform and returns transform.ErrShortSrc.
+func (r *NormReplacer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { r.w.done(nDst, nSrc) }()
+
+	nDst, err = r.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	if nDst, nSrc, err = r.w.copyRest(dst, src, nDst); err != nil {
+		return nDst, nSrc, err
+	}
+
+	for nSrc < len(src) {
+		n, rule, short := r.match(src[nSrc:], atEOF)
+		if short {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+
+		if rule != -1 {
+			_, new := r.rules.At(rule)
+			nDst, err = r.w.replace(dst, nDst, nSrc, nSrc+n, new)
+			nSrc += n
+		} else {
+			// copy a segment
+			n := r.form.NextBoundary(src[nSrc:], atEOF)
+			if n == -1 {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			nDst, nSrc, err = r.w.copyToken(dst, src, nDst, nSrc, n)
+		}
+
+		if err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	return nDst, nSrc, nil
+}
+
+// match returns the index of the first rule which matches the beginning of src
+// under the normalization form, and the length of the matched bytes in src.
+// If no rule matches, rule is -1.
+func (r *NormReplacer) match(src []byte, atEOF bool) (n, rule int, short bool) {
+	for i, old := range r.rules.old {
+		if len(old) == 0 {
+			continue
+		}
+
+		// normalize src segment by segment while it is a prefix of old
+		r.seg = r.seg[:0]
+		n := 0
+		for bytes.HasPrefix(old, r.seg) && len(r.seg) < len(old) {
+			if n == len(src) {
+				if !atEOF {
+					return 0, -1, true
+				}
+				break
+			}
+
+			size := r.form.NextBoundary(src[n:], atEOF)
+			if size == -1 {
+				return 0, -1, true
+			}
+			r.seg = r.form.Append(r.seg, src[n:n+size]...)
+			n += size
+		}
+
+		if bytes.Equal(r.seg, old) {
+			return n, i, false
+		}
+	}
+
+	return 0, -1, false
+}
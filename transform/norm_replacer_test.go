@@ -0,0 +1,81 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	"golang.org/x/text/unicode/norm"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestNormReplacer_Transform(t *testing.T) {
+	const (
+		cafeNFC = "caf\u00e9"
+		cafeNFD = "cafe\u0301"
+	)
+
+	cases := []struct {
+		form  norm.Form
+		table ReplaceStringTable
+		input string
+
+		expected string
+		history  []HistoryEntry
+	}{
+		{
+			form:     norm.NFC,
+			table:    ReplaceStringTable{cafeNFC, "CAFE"},
+			input:    cafeNFD + " " + cafeNFC + " " + "cafe",
+			expected: "CAFE CAFE cafe",
+			history:  []HistoryEntry{{0, 6, 0, 4}, {7, 12, 5, 9}},
+		},
+		{
+			// a match must end at a boundary of segments
+			form:     norm.NFC,
+			table:    ReplaceStringTable{"cafe", "CAFE"},
+			input:    cafeNFD + " cafe",
+			expected: cafeNFD + " CAFE",
+			history:  []HistoryEntry{{7, 11, 7, 11}},
+		},
+		{
+			// non-matching text is not normalized
+			form:     norm.NFD,
+			table:    ReplaceStringTable{"x", "y"},
+			input:    cafeNFC + " x",
+			expected: cafeNFC + " y",
+			history:  []HistoryEntry{{6, 7, 6, 7}},
+		},
+		{
+			form:     norm.NFKC,
+			table:    ReplaceStringTable{"file", "FILE"},
+			input:    "ﬁle ｆｉｌｅ",
+			expected: "FILE FILE",
+			history:  []HistoryEntry{{0, 5, 0, 4}, {6, 18, 5, 9}},
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {13, 1}, {14, 3}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				r := NewNormReplacer(c.form, c.table, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(r, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
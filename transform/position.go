@@ -0,0 +1,107 @@
+package transform
+
+import (
+	"go/token"
+	"sort"
+)
+
+// PositionMapper maps positions in the destination of replacing back to the source.
+// It is useful to report errors of compiling or parsing the replaced source code
+// with positions of the original source code.
+type PositionMapper struct {
+	filename string
+	entries  []HistoryEntry // sorted by Dst0
+	lines    []int          // offsets of the first bytes of lines in src
+	size     int
+}
+
+// NewPositionMapper creates a new PositionMapper from the histories of replacing src.
+// filename is the name of the original file.
+// If filename is empty, the mapped position has the filename of given position.
+func NewPositionMapper(history *ReplaceHistory, filename string, src []byte) *PositionMapper {
+	m := &PositionMapper{
+		filename: filename,
+		lines:    []int{0},
+		size:     len(src),
+	}
+
+	history.Iterate(func(src0, src1, dst0, dst1 int) bool {
+		m.entries = append(m.entries, HistoryEntry{Src0: src0, Src1: src1, Dst0: dst0, Dst1: dst1})
+		return true
+	})
+	sort.SliceStable(m.entries, func(i, j int) bool {
+		return m.entries[i].Dst0 < m.entries[j].Dst0
+	})
+
+	for i, b := range src {
+		if b == '\n' {
+			m.lines = append(m.lines, i+1)
+		}
+	}
+
+	return m
+}
+
+// Offset maps an offset in the destination to the offset in the source.
+// An offset in a replaced text is mapped into the range of the original text.
+func (m *PositionMapper) Offset(dst int) int {
+	// the last entry which begins at or before dst
+	i := sort.Search(len(m.entries), func(i int) bool {
+		return m.entries[i].Dst0 > dst
+	}) - 1
+
+	if i < 0 {
+		return dst
+	}
+
+	e := m.entries[i]
+	if dst < e.Dst1 {
+		n := dst - e.Dst0
+		if max := e.Src1 - e.Src0 - 1; n > max {
+			n = max
+		}
+		if n < 0 {
+			n = 0
+		}
+		return e.Src0 + n
+	}
+
+	return e.Src1 + dst - e.Dst1
+}
+
+// Position maps a position in the destination to the position in the source.
+// The Offset field of pos must be valid, such as a position which is given by token.FileSet.
+// Line and Column of the mapped position are computed from the source.
+// If pos is not valid, Position returns pos as it is.
+func (m *PositionMapper) Position(pos token.Position) token.Position {
+	if !pos.IsValid() {
+		return pos
+	}
+
+	off := m.Offset(pos.Offset)
+	if off > m.size {
+		off = m.size
+	}
+
+	line := sort.Search(len(m.lines), func(i int) bool {
+		return m.lines[i] > off
+	})
+
+	mapped := token.Position{
+		Filename: m.filename,
+		Offset:   off,
+		Line:     line,
+		Column:   off - m.lines[line-1] + 1,
+	}
+	if mapped.Filename == "" {
+		mapped.Filename = pos.Filename
+	}
+
+	return mapped
+}
+
+// FileSetPosition returns the position in the source of given token.Pos
+// which is a position in the destination in fset.
+func (m *PositionMapper) FileSetPosition(fset *token.FileSet, pos token.Pos) token.Position {
+	return m.Position(fset.Position(pos))
+}
@@ -0,0 +1,53 @@
+package transform_test
+
+import (
+	"errors"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestPositionMapper_Position(t *testing.T) {
+	const src = "package p\n\nvar NAME = \"NAME\"\nvar x = NAME @\n"
+
+	history := NewReplaceHistory()
+	dst, _, err := transform.String(NewReplacer([]byte("NAME"), []byte("longer_name"), history), src)
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	fset := token.NewFileSet()
+	_, err = parser.ParseFile(fset, "gen.go", dst, 0)
+	var errs scanner.ErrorList
+	if !errors.As(err, &errs) || len(errs) == 0 {
+		t.Fatal("a syntax error is expected but", err)
+	}
+
+	m := NewPositionMapper(history, "orig.go", []byte(src))
+	pos := m.Position(errs[0].Pos)
+	expected := token.Position{Filename: "orig.go", Offset: 42, Line: 4, Column: 14}
+	if pos != expected {
+		t.Errorf("the position is expected %v but %v (%v)", expected, pos, errs[0].Pos)
+	}
+}
+
+func TestPositionMapper_Offset(t *testing.T) {
+	history := NewReplaceHistory()
+	// "xabcxabc" -> "xAxA"
+	_, _, err := transform.String(NewReplacer([]byte("abc"), []byte("A"), history), "xabcxabc")
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	m := NewPositionMapper(history, "", nil)
+	for dst, expected := range []int{0, 1, 4, 5, 8} {
+		if got := m.Offset(dst); got != expected {
+			t.Errorf("the offset of %d is expected %d but %d", dst, expected, got)
+		}
+	}
+}
@@ -0,0 +1,279 @@
+package transform
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"hash"
+	"unicode"
+	"unicode/utf8"
+)
+
+// DefaultPseudonymAlphabet is the default alphabet of pseudonyms.
+const DefaultPseudonymAlphabet = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+// DefaultPseudonymLen is the default number of runes of a pseudonym.
+const DefaultPseudonymLen = 16
+
+// PseudonymOptions represents options of Pseudonymizer.
+type PseudonymOptions struct {
+	// Alphabet is runes which a pseudonym consists of.
+	// If Alphabet is empty, DefaultPseudonymAlphabet is used.
+	Alphabet string
+	// Len is the number of runes of a pseudonym.
+	// If Len is 0, DefaultPseudonymLen is used.
+	Len int
+	// PreserveFormat makes a pseudonym have the same format as the original value.
+	// Each ASCII digit is replaced with a digit, each ASCII lower and upper letter
+	// is replaced with a lower and upper letter and other ASCII bytes are kept,
+	// so "555-123-4567" may become "904-771-0382".
+	// Each non-ASCII letter, digit and mark is replaced with a rune of the same script and class,
+	// such as a kanji with a kanji, so "山田太郎@example.com" may become "祥覧沖垣@yhyjvfw.akv".
+	// Other non-ASCII runes such as punctuation are kept, and invalid bytes are replaced with invalid bytes.
+	// Alphabet and Len are ignored.
+	PreserveFormat bool
+	// Prefix is prepended to pseudonyms such as "user_".
+	// It is not prepended with PreserveFormat.
+	Prefix string
+}
+
+// Pseudonymizer derives pseudonyms from values with HMAC-SHA256 and a secret key.
+// The same value always becomes the same pseudonym with the same key and options,
+// but the value cannot be recovered from the pseudonym without the key.
+// It is safe for concurrent use by multiple goroutines.
+type Pseudonymizer struct {
+	key      []byte
+	alphabet []rune
+	opts     PseudonymOptions
+}
+
+// NewPseudonymizer creates a new Pseudonymizer with key.
+// If opts is nil, the default options are used.
+// It panics if key is empty, because values could be recovered from pseudonyms
+// by a dictionary attack without a secret key.
+// It also panics if the alphabet has more than 65536 runes.
+func NewPseudonymizer(key []byte, opts *PseudonymOptions) *Pseudonymizer {
+	if len(key) == 0 {
+		panic("transform: the key of Pseudonymizer must not be empty")
+	}
+
+	p := &Pseudonymizer{
+		key: append([]byte(nil), key...),
+	}
+
+	if opts != nil {
+		p.opts = *opts
+	}
+
+	if p.opts.Alphabet == "" {
+		p.opts.Alphabet = DefaultPseudonymAlphabet
+	}
+	p.alphabet = []rune(p.opts.Alphabet)
+	if len(p.alphabet) > 1<<16 {
+		panic("transform: the alphabet of Pseudonymizer is too large")
+	}
+
+	if p.opts.Len <= 0 {
+		p.opts.Len = DefaultPseudonymLen
+	}
+
+	return p
+}
+
+// Pseudonym returns the pseudonym of value.
+func (p *Pseudonymizer) Pseudonym(value []byte) []byte {
+	s := newHMACStream(p.key, value)
+
+	if p.opts.PreserveFormat {
+		b := make([]byte, 0, len(value))
+		for i := 0; i < len(value); {
+			r, n := utf8.DecodeRune(value[i:])
+			switch {
+			case '0' <= r && r <= '9':
+				b = append(b, '0'+s.intn(10))
+			case 'a' <= r && r <= 'z':
+				b = append(b, 'a'+s.intn(26))
+			case 'A' <= r && r <= 'Z':
+				b = append(b, 'A'+s.intn(26))
+			case r == utf8.RuneError && n == 1:
+				// a continuation byte cannot be a part of a valid rune
+				b = append(b, 0x80+s.intn(0x40))
+			case r >= utf8.RuneSelf:
+				b = utf8.AppendRune(b, s.runeLike(r))
+			default:
+				b = append(b, byte(r))
+			}
+			i += n
+		}
+		return b
+	}
+
+	b := make([]byte, 0, len(p.opts.Prefix)+p.opts.Len)
+	b = append(b, p.opts.Prefix...)
+	for i := 0; i < p.opts.Len; i++ {
+		b = utf8.AppendRune(b, p.alphabet[s.index(len(p.alphabet))])
+	}
+	return b
+}
+
+// Mask returns the pseudonym of match.
+// It can be used as RedactOptions.Func with MaskFunc.
+func (p *Pseudonymizer) Mask(_ *PIIDetector, match []byte) []byte {
+	return p.Pseudonym(match)
+}
+
+// Table returns a ReplaceTable which replaces old of each rule in t to its pseudonym.
+// new of rules in t are ignored.
+func (p *Pseudonymizer) Table(t ReplaceTable) ReplaceTable {
+	var table ReplaceByteTable
+	for i := 0; i < t.Len(); i++ {
+		old, _ := t.At(i)
+		table.Add(old, p.Pseudonym(old))
+	}
+	return table
+}
+
+// hmacStream is a stream of pseudorandom bytes
+// which are derived from a key and a value like HKDF-Expand.
+type hmacStream struct {
+	mac     hash.Hash
+	value   []byte
+	block   []byte
+	counter byte
+	pos     int
+}
+
+func newHMACStream(key, value []byte) *hmacStream {
+	return &hmacStream{
+		mac:   hmac.New(sha256.New, key),
+		value: value,
+	}
+}
+
+// next returns the next byte of the stream.
+func (s *hmacStream) next() byte {
+	if s.pos == len(s.block) {
+		// T(i) = HMAC(key, T(i-1) | value | i)
+		s.counter++
+		s.mac.Reset()
+		s.mac.Write(s.block)
+		s.mac.Write(s.value)
+		s.mac.Write([]byte{s.counter})
+		s.block = s.mac.Sum(s.block[:0])
+		s.pos = 0
+	}
+
+	b := s.block[s.pos]
+	s.pos++
+	return b
+}
+
+// index returns a uniform random integer in [0, n).
+// It rejects biased bytes and uses two bytes when n is larger than 256.
+func (s *hmacStream) index(n int) int {
+	size := 256
+	if n > size {
+		size = 1 << 16
+	}
+
+	limit := size - size%n
+	for {
+		v := int(s.next())
+		if size > 256 {
+			v = v<<8 | int(s.next())
+		}
+
+		if v < limit {
+			return v % n
+		}
+	}
+}
+
+// runeLike returns a random rune which has the same script and class as r,
+// such as a kanji for a kanji or a Cyrillic upper letter for a Cyrillic upper letter.
+// A digit is replaced with a digit of the same digit set.
+// If r is not a letter, a digit or a mark, r is returned.
+func (s *hmacStream) runeLike(r rune) rune {
+	class := runeClass(r)
+	if class == runeOther {
+		return r
+	}
+
+	table := unicode.Digit
+	if class != runeDigit {
+		table = scriptOf(r)
+		if table == nil {
+			return r
+		}
+	}
+
+	lo, hi, stride := rangeOf(table, r)
+	n := int((hi-lo)/stride) + 1
+	if n > 1<<16 {
+		n = 1 << 16
+	}
+
+	// r itself has the class, so the loop ends
+	for {
+		c := lo + rune(s.index(n))*stride
+		if runeClass(c) == class {
+			return c
+		}
+	}
+}
+
+// Classes of runes which runeLike keeps.
+const (
+	runeOther = iota
+	runeDigit
+	runeUpper
+	runeLower
+	runeLetter // other letters such as kanji
+	runeMark
+)
+
+func runeClass(r rune) int {
+	switch {
+	case unicode.IsDigit(r):
+		return runeDigit
+	case unicode.IsUpper(r):
+		return runeUpper
+	case unicode.IsLower(r):
+		return runeLower
+	case unicode.IsLetter(r):
+		return runeLetter
+	case unicode.IsMark(r):
+		return runeMark
+	}
+	return runeOther
+}
+
+// scriptOf returns the table of the script of r, or nil if r does not have a script.
+func scriptOf(r rune) *unicode.RangeTable {
+	for _, t := range unicode.Scripts {
+		if unicode.Is(t, r) {
+			return t
+		}
+	}
+	return nil
+}
+
+// rangeOf returns the range of table which contains r.
+// r must be in table.
+func rangeOf(table *unicode.RangeTable, r rune) (lo, hi, stride rune) {
+	for _, rg := range table.R16 {
+		if rune(rg.Lo) <= r && r <= rune(rg.Hi) && (r-rune(rg.Lo))%rune(rg.Stride) == 0 {
+			return rune(rg.Lo), rune(rg.Hi), rune(rg.Stride)
+		}
+	}
+	for _, rg := range table.R32 {
+		if rune(rg.Lo) <= r && r <= rune(rg.Hi) && (r-rune(rg.Lo))%rune(rg.Stride) == 0 {
+			return rune(rg.Lo), rune(rg.Hi), rune(rg.Stride)
+		}
+	}
+	return r, r, 1
+}
+
+// intn returns a uniform random byte in [0, n).
+func (s *hmacStream) intn(n int) byte {
+	return byte(s.index(n))
+}
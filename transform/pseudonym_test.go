@@ -0,0 +1,111 @@
+package transform_test
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestPseudonymizer_Pseudonym(t *testing.T) {
+	key := []byte("secret")
+	cases := []struct {
+		opts  *PseudonymOptions
+		value string
+
+		pattern string
+	}{
+		{nil, "alice@example.com", `^[0-9a-z]{16}$`},
+		{&PseudonymOptions{Alphabet: "AB", Len: 8}, "alice", `^[AB]{8}$`},
+		{&PseudonymOptions{Alphabet: "あいう", Len: 4, Prefix: "id-"}, "alice", `^id-[あいう]{4}$`},
+		{&PseudonymOptions{PreserveFormat: true}, "555-123-4567", `^[0-9]{3}-[0-9]{3}-[0-9]{4}$`},
+		{&PseudonymOptions{PreserveFormat: true}, "Alice.B@x1", `^[A-Z][a-z]{4}\.[A-Z]@[a-z][0-9]$`},
+		{&PseudonymOptions{PreserveFormat: true}, "山田太郎@example.com", `^\p{Han}{4}@[a-z]{7}\.[a-z]{3}$`},
+		{&PseudonymOptions{PreserveFormat: true}, "Иван José", `^\p{Cyrillic}\p{Ll}{3} \p{Lu}[a-z]{2}\p{Latin}$`},
+		{&PseudonymOptions{PreserveFormat: true}, "やまだ\u0663\u0664\xff", `^\p{Hiragana}{3}[\x{0660}-\x{0669}]{2}\x{FFFD}$`},
+	}
+
+	for i, c := range cases {
+		c := c
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			p := NewPseudonymizer(key, c.opts)
+			got := string(p.Pseudonym([]byte(c.value)))
+			if !regexp.MustCompile(c.pattern).MatchString(got) {
+				t.Errorf("the pseudonym of %q is expected to match %s but %q", c.value, c.pattern, got)
+			}
+
+			for _, r := range c.value {
+				if r >= utf8.RuneSelf && unicode.IsLetter(r) && strings.ContainsRune(got, r) {
+					t.Errorf("the pseudonym of %q contains %q of the value: %q", c.value, r, got)
+				}
+			}
+
+			if again := string(p.Pseudonym([]byte(c.value))); again != got {
+				t.Errorf("the pseudonym of %q is not deterministic: %q and %q", c.value, got, again)
+			}
+
+			if other := string(NewPseudonymizer([]byte("other"), c.opts).Pseudonym([]byte(c.value))); other == got {
+				t.Errorf("the pseudonym of %q does not depend on the key: %q", c.value, got)
+			}
+		})
+	}
+}
+
+func TestPseudonymizer_Mask(t *testing.T) {
+	p := NewPseudonymizer([]byte("secret"), &PseudonymOptions{Prefix: "user_", Len: 8})
+	alice, bob := string(p.Pseudonym([]byte("alice@example.com"))), string(p.Pseudonym([]byte("bob@example.com")))
+	if alice == bob {
+		t.Fatalf("pseudonyms of different values are same: %q", alice)
+	}
+
+	cases := []struct {
+		input string
+
+		expected string
+	}{
+		{
+			input:    "from alice@example.com to bob@example.com cc alice@example.com",
+			expected: fmt.Sprintf("from %s to %s cc %s", alice, bob, alice),
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {60, 1}, {70, 7}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				r := NewRedactor(&RedactOptions{Mask: MaskFunc, Func: p.Mask, MaxLen: 32}, nil)
+				got, err := transformChunks(r, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				// literal table
+				got, err = transformChunks(ReplaceAll(p.Table(ReplaceStringTable{"alice@example.com", "", "bob@example.com", ""})), c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output with the table is expected %q but %q", c.expected, got)
+				}
+			})
+		}
+	}
+}
+
+func TestNewPseudonymizer_EmptyKey(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a panic is expected but not occured")
+		}
+	}()
+	NewPseudonymizer(nil, nil)
+}
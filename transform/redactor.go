@@ -0,0 +1,486 @@
+package transform
+
+import (
+	"bytes"
+	"net/netip"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// PIIDetector detects a kind of personally identifiable information.
+type PIIDetector struct {
+	// Name is the name of the detector such as "EMAIL".
+	// It is used as the label of histories and the placeholder of MaskPlaceholder.
+	Name string
+	// Pattern matches candidates. A match must not contain a new line.
+	Pattern *regexp.Regexp
+	// Validate reports whether a candidate is actually the information.
+	// If Validate is nil, all candidates are accepted.
+	Validate func(match []byte) bool
+	// End matches the end of a block such as "-----END PRIVATE KEY-----".
+	// If End is not nil, a match of Pattern begins a block which continues
+	// until a match of End in the same or later lines, or the end of the input.
+	// The whole block is masked by the mask of the match of Pattern.
+	End *regexp.Regexp
+	// AllowAdjacent reports whether a match can be adjacent to letters, digits or '_'.
+	// It is useful for secrets which are often embedded in identifiers such as "KEY_backup".
+	AllowAdjacent bool
+}
+
+// Built-in detectors.
+// A match of them must not be adjacent to letters, digits or '_',
+// and must not be continued by other digits such as the rest of a longer number.
+var (
+	// EmailDetector detects email addresses.
+	EmailDetector = &PIIDetector{
+		Name:    "EMAIL",
+		Pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9-]+(?:\.[A-Za-z0-9-]+)*\.[A-Za-z]{2,}`),
+	}
+
+	// IPv4Detector detects IPv4 addresses.
+	IPv4Detector = &PIIDetector{
+		Name:    "IPV4",
+		Pattern: regexp.MustCompile(`(?:(?:25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])\.){3}(?:25[0-5]|2[0-4][0-9]|1[0-9][0-9]|[1-9]?[0-9])`),
+	}
+
+	// IPv6Detector detects IPv6 addresses such as "2001:db8::1".
+	IPv6Detector = &PIIDetector{
+		Name:    "IPV6",
+		Pattern: regexp.MustCompile(`(?i)(?:[0-9a-f]{1,4})?(?::[0-9a-f]{0,4}){2,7}`),
+		Validate: func(match []byte) bool {
+			addr, err := netip.ParseAddr(string(match))
+			return err == nil && addr.Is6() && bytes.ContainsFunc(match, func(r rune) bool {
+				return r != ':' && r != '0'
+			})
+		},
+	}
+
+	// CreditCardDetector detects credit card numbers which pass the Luhn check.
+	// Digits can be separated by spaces or hyphens.
+	CreditCardDetector = &PIIDetector{
+		Name:     "CREDIT_CARD",
+		Pattern:  regexp.MustCompile(`(?:[0-9][ -]?){12,18}[0-9]`),
+		Validate: luhn,
+	}
+
+	// PhoneDetector detects phone numbers such as "+1 555-123-4567" and "(03) 1234-5678".
+	PhoneDetector = &PIIDetector{
+		Name:    "PHONE",
+		Pattern: regexp.MustCompile(`(?:\+[0-9]{1,3}[ .-]?)?(?:\([0-9]{1,4}\)[ .-]?)?[0-9]{2,4}[ .-][0-9]{3,4}[ .-][0-9]{3,4}`),
+	}
+
+	// JWTDetector detects JSON Web Tokens.
+	JWTDetector = &PIIDetector{
+		Name:    "JWT",
+		Pattern: regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	}
+
+	// APIKeyDetector detects API keys which have well-known prefixes,
+	// such as "sk_live_", "AKIA", "ghp_", "xoxb-" and "AIza".
+	APIKeyDetector = &PIIDetector{
+		Name:    "API_KEY",
+		Pattern: regexp.MustCompile(`(?:sk|pk|rk)_(?:live|test)_[A-Za-z0-9]{16,}|AKIA[0-9A-Z]{16}|gh[pousr]_[A-Za-z0-9]{36}|xox[abposr]-[A-Za-z0-9-]{10,}|AIza[0-9A-Za-z_-]{35}`),
+	}
+)
+
+// DefaultPIIDetectors are detectors which Redactor uses by default.
+// If matches of detectors overlap, the earlier match is applied
+// and the earlier detector in the slice is prior to later ones at the same position.
+var DefaultPIIDetectors = []*PIIDetector{
+	JWTDetector,
+	APIKeyDetector,
+	EmailDetector,
+	IPv6Detector,
+	IPv4Detector,
+	CreditCardDetector,
+	PhoneDetector,
+}
+
+// luhn reports whether digits in b pass the Luhn check.
+func luhn(b []byte) bool {
+	var sum, n int
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < '0' || '9' < b[i] {
+			continue
+		}
+
+		d := int(b[i] - '0')
+		if n%2 == 1 {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		n++
+	}
+	return 13 <= n && n <= 19 && sum%10 == 0
+}
+
+// PIIMask represents how Redactor masks detected information.
+type PIIMask int
+
+const (
+	// MaskFixed replaces detected information with RedactOptions.Text.
+	MaskFixed PIIMask = iota
+	// MaskStars replaces each rune of detected information with '*'.
+	MaskStars
+	// MaskPlaceholder replaces detected information with the name of the detector
+	// enclosed by '<' and '>', such as "<EMAIL>".
+	MaskPlaceholder
+	// MaskFunc replaces detected information with the result of RedactOptions.Func.
+	MaskFunc
+)
+
+// DefaultRedactText is the default text of MaskFixed.
+const DefaultRedactText = "[REDACTED]"
+
+// DefaultMaxPIILen is the default maximum length of detected information.
+const DefaultMaxPIILen = 1024
+
+// RedactOptions represents options of Redactor.
+type RedactOptions struct {
+	// Detectors are used to detect information.
+	// If Detectors is nil, DefaultPIIDetectors is used.
+	Detectors []*PIIDetector
+	// Mask is how detected information is masked.
+	Mask PIIMask
+	// Text is used with MaskFixed. If Text is empty, DefaultRedactText is used.
+	Text string
+	// Func is used with MaskFunc. It returns the replacement of match
+	// which is detected by d, such as Pseudonymizer.Mask.
+	Func func(d *PIIDetector, match []byte) []byte
+	// MaxLen is the maximum length of detected information.
+	// Redactor looks ahead until the end of a line or MaxLen bytes,
+	// so information in a line which is longer than MaxLen may not be detected.
+	// If MaxLen is 0, DefaultMaxPIILen is used.
+	// MaxLen is capped at 2048 bytes, half of the source buffer of transform.Reader
+	// and transform.Writer, so that the lookahead fits in the buffer
+	// and each Transform call can consume the rest of the buffer.
+	MaxLen int
+}
+
+// Redactor masks personally identifiable information such as email addresses.
+// It implements transform.Transformer.
+type Redactor struct {
+	opts RedactOptions
+	prev []byte // last bytes which were consumed by previous Transform calls
+	w    streamWriter
+
+	// the current block; block is nil if it is not in a block
+	block     *PIIDetector
+	blockSrc0 int
+	blockDst0 int
+	blockDst1 int
+}
+
+var _ transform.Transformer = (*Redactor)(nil)
+
+// NewRedactor creates a new Redactor.
+// If opts is nil, the default options are used.
+// It panics if the mask is MaskFunc and opts.Func is nil.
+//
+// If history is not nil, Redactor records histories of masking.
+// If history implements LabeledHistorySink such as *ReplaceHistory,
+// each history is labeled with the name of the detector.
+func NewRedactor(opts *RedactOptions, history HistorySink) *Redactor {
+	r := &Redactor{
+		w: newStreamWriter(history),
+	}
+
+	if opts != nil {
+		r.opts = *opts
+	}
+
+	if r.opts.Detectors == nil {
+		r.opts.Detectors = DefaultPIIDetectors
+	}
+
+	if r.opts.Text == "" {
+		r.opts.Text = DefaultRedactText
+	}
+
+	if r.opts.Mask == MaskFunc && r.opts.Func == nil {
+		panic("transform: RedactOptions.Func must not be nil with MaskFunc")
+	}
+
+	switch {
+	case r.opts.MaxLen <= 0:
+		r.opts.MaxLen = DefaultMaxPIILen
+	case r.opts.MaxLen > maxLookahead/2:
+		r.opts.MaxLen = maxLookahead / 2
+	}
+
+	return r
+}
+
+// Reset implements transform.Transformer.Reset.
+func (r *Redactor) Reset() {
+	r.prev = r.prev[:0]
+	r.block = nil
+	r.w.reset()
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When src does not contain a new line and is shorter than the maximum length
+// and atEOF is false, the Redactor stops to transform and returns transform.ErrShortSrc.
+func (r *Redactor) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() {
+		r.prev = lastBytes(r.prev, src[:nSrc])
+		r.w.done(nDst, nSrc)
+	}()
+
+	nDst, err = r.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	for nSrc < len(src) || atEOF && r.block != nil {
+		rest := src[nSrc:]
+
+		// detect information which begins before limit
+		limit := len(rest)
+		if !atEOF {
+			switch i := bytes.LastIndexByte(rest, '\n'); {
+			case i != -1:
+				limit = i + 1
+			case len(rest) > r.opts.MaxLen:
+				limit = len(rest) - r.opts.MaxLen
+			default:
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+		}
+
+		if r.block != nil {
+			nSrc += r.skipBlock(rest, r.w.offSrc+nSrc, limit, atEOF)
+			continue
+		}
+
+		base, end := nSrc, nSrc+limit
+		prev := lastBytes(append([]byte(nil), r.prev...), src[:nSrc])
+		for _, m := range r.find(prev, rest, limit) {
+			if nDst, nSrc, err = r.w.copy(dst, src, nDst, nSrc, base+m.start); err != nil {
+				return nDst, nSrc, err
+			}
+
+			d := r.opts.Detectors[m.detector]
+			mask := r.mask(d, src[base+m.start:base+m.end])
+			dst0 := nDst
+			nDst, err = r.w.write(dst, nDst, mask)
+			if d.End != nil {
+				// the history is recorded at the end of the block
+				r.block = d
+				r.blockSrc0 = r.w.offSrc + nSrc
+				r.blockDst0, r.blockDst1 = r.w.offDst+dst0, r.w.offDst+dst0+len(mask)
+			} else {
+				addLabeledHistory(r.w.history, d.Name,
+					r.w.offSrc+nSrc, r.w.offSrc+base+m.end,
+					r.w.offDst+dst0, r.w.offDst+dst0+len(mask))
+			}
+			nSrc = base + m.end
+			if err != nil {
+				return nDst, nSrc, err
+			}
+
+			if r.block != nil {
+				// following matches are in the block
+				break
+			}
+		}
+
+		if r.block != nil {
+			continue
+		}
+
+		if end < nSrc {
+			end = nSrc
+		}
+		if nDst, nSrc, err = r.w.copy(dst, src, nDst, nSrc, end); err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	return nDst, nSrc, nil
+}
+
+// skipBlock skips bytes of the current block in src and returns the number of skipped bytes.
+// off is the offset of src in the input.
+// It ends the block if src contains the end of the block which begins before limit, or atEOF is true.
+func (r *Redactor) skipBlock(src []byte, off, limit int, atEOF bool) int {
+	n := limit
+	if loc := r.block.End.FindIndex(src); loc != nil && loc[0] < limit {
+		n = loc[1]
+	} else if !atEOF {
+		return n
+	}
+
+	addLabeledHistory(r.w.history, r.block.Name,
+		r.blockSrc0, off+n,
+		r.blockDst0, r.blockDst1)
+	r.block = nil
+	return n
+}
+
+// piiMatch represents a match of a detector.
+type piiMatch struct {
+	start, end int
+	detector   int
+}
+
+// find returns non-overlapping matches in src which begin before limit.
+// prev is bytes just before src.
+func (r *Redactor) find(prev, src []byte, limit int) []piiMatch {
+	var ms []piiMatch
+	for i, d := range r.opts.Detectors {
+		for _, loc := range d.Pattern.FindAllIndex(src, -1) {
+			if loc[0] >= limit || loc[0] == loc[1] {
+				continue
+			}
+
+			end := accept(d, prev, src, loc[0], loc[1])
+			if end == -1 {
+				continue
+			}
+
+			ms = append(ms, piiMatch{start: loc[0], end: end, detector: i})
+		}
+	}
+
+	sort.SliceStable(ms, func(i, j int) bool {
+		if ms[i].start != ms[j].start {
+			return ms[i].start < ms[j].start
+		}
+		return ms[i].detector < ms[j].detector
+	})
+
+	// remove overlapping matches
+	var pos int
+	result := ms[:0]
+	for _, m := range ms {
+		if m.start >= pos {
+			result = append(result, m)
+			pos = m.end
+		}
+	}
+
+	return result
+}
+
+// accept returns the end of the information which begins at start
+// in a candidate src[start:end] which is matched by d, or -1 if there is no information.
+// If the candidate is not isolated or not valid, shorter candidates which end
+// at a boundary of words in it are tried from the longest,
+// because a pattern can overrun into following digits such as "12" of "4111111111111111 12.50".
+func accept(d *PIIDetector, prev, src []byte, start, end int) int {
+	if d.AllowAdjacent {
+		if d.Validate == nil || d.Validate(src[start:end]) {
+			return end
+		}
+		return -1
+	}
+
+	if isIsolated(prev, src, start, end) && (d.Validate == nil || d.Validate(src[start:end])) {
+		return end
+	}
+
+	if !isIsolatedStart(prev, src, start) {
+		return -1
+	}
+
+	for e := end - 1; e > start; e-- {
+		// a shorter candidate is followed by a separator in the longer one
+		if !isWordByte(src[e-1]) || isWordByte(src[e]) {
+			continue
+		}
+
+		if loc := d.Pattern.FindIndex(src[start:e]); loc == nil || loc[0] != 0 || loc[1] != e-start {
+			continue
+		}
+
+		if d.Validate == nil || d.Validate(src[start:e]) {
+			return e
+		}
+	}
+
+	return -1
+}
+
+// isIsolated reports whether src[start:end] is not a part of a longer token.
+// It must not be adjacent to a word, and a match which begins or ends with a digit
+// must not be continued by a separator and another digit such as "1234 5678".
+// prev is bytes just before src.
+func isIsolated(prev, src []byte, start, end int) bool {
+	return isIsolatedStart(prev, src, start) && isIsolatedEnd(src, end)
+}
+
+// isIsolatedStart reports whether a match which begins at src[start] is isolated from its preceding bytes.
+func isIsolatedStart(prev, src []byte, start int) bool {
+	at := func(i int) byte {
+		if i < 0 && len(prev)+i >= 0 {
+			return prev[len(prev)+i]
+		}
+		if i >= 0 {
+			return src[i]
+		}
+		return 0
+	}
+
+	return !isWordByte(at(start-1)) &&
+		!(isDigit(src[start]) && isSeparator(at(start-1)) && isDigit(at(start-2)))
+}
+
+// isIsolatedEnd reports whether a match which ends at src[end] is isolated from its following bytes.
+func isIsolatedEnd(src []byte, end int) bool {
+	at := func(i int) byte {
+		if i < len(src) {
+			return src[i]
+		}
+		return 0
+	}
+
+	return !isWordByte(at(end)) &&
+		!(isDigit(src[end-1]) && isSeparator(at(end)) && isDigit(at(end+1)))
+}
+
+// lastBytes appends b to prev and returns at most its last 2 bytes,
+// which are enough as the context of isIsolated.
+func lastBytes(prev, b []byte) []byte {
+	if len(b) >= 2 {
+		return append(prev[:0], b[len(b)-2:]...)
+	}
+	prev = append(prev, b...)
+	if len(prev) > 2 {
+		prev = append(prev[:0], prev[len(prev)-2:]...)
+	}
+	return prev
+}
+
+func isDigit(b byte) bool {
+	return '0' <= b && b <= '9'
+}
+
+func isSeparator(b byte) bool {
+	return b == ' ' || b == '.' || b == '-'
+}
+
+func isWordByte(b byte) bool {
+	return 'a' <= b && b <= 'z' || 'A' <= b && b <= 'Z' || '0' <= b && b <= '9' || b == '_'
+}
+
+// mask returns the mask of match which is detected by d.
+func (r *Redactor) mask(d *PIIDetector, match []byte) []byte {
+	switch r.opts.Mask {
+	case MaskStars:
+		return []byte(strings.Repeat("*", utf8.RuneCount(match)))
+	case MaskPlaceholder:
+		return []byte("<" + d.Name + ">")
+	case MaskFunc:
+		return r.opts.Func(d, match)
+	}
+	return []byte(r.opts.Text)
+}
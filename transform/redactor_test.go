@@ -0,0 +1,133 @@
+package transform_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestRedactor_Transform(t *testing.T) {
+	cases := []struct {
+		mask  PIIMask
+		input string
+
+		expected string
+		history  []string
+	}{
+		{
+			mask:     MaskFixed,
+			input:    "mail alice@example.com\nor call +1 555-123-4567\n",
+			expected: "mail [REDACTED]\nor call [REDACTED]\n",
+			history:  []string{"EMAIL 5 22 5 15", "PHONE 31 46 24 34"},
+		},
+		{
+			mask:     MaskPlaceholder,
+			input:    "ip 192.168.0.1 and 2001:db8::1, card 4111 1111 1111 1111",
+			expected: "ip <IPV4> and <IPV6>, card <CREDIT_CARD>",
+			history:  []string{"IPV4 3 14 3 9", "IPV6 19 30 14 20", "CREDIT_CARD 37 56 27 40"},
+		},
+		{
+			mask:     MaskStars,
+			input:    "key sk_test_abcdefghijklmnop1234 here",
+			expected: "key **************************** here",
+			history:  []string{"API_KEY 4 32 4 32"},
+		},
+		{
+			// the pattern overruns into "12" but the card number is detected
+			mask:     MaskPlaceholder,
+			input:    "paid 4111111111111111 12.50 today",
+			expected: "paid <CREDIT_CARD> 12.50 today",
+			history:  []string{"CREDIT_CARD 5 21 5 18"},
+		},
+		{
+			// not information
+			mask:     MaskPlaceholder,
+			input:    "id 1234 std::cout at 12:30:45 v1.2.3 a@b 4111 1111 1111 1112 tel 03-1234-5678-9",
+			expected: "id 1234 std::cout at 12:30:45 v1.2.3 a@b 4111 1111 1111 1112 tel 03-1234-5678-9",
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {40, 1}, {50, 7}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []string
+				opts := &RedactOptions{Mask: c.mask, MaxLen: 32}
+				r := NewRedactor(opts, LabeledHistoryFunc(func(label string, src0, src1, dst0, dst1 int) {
+					history = append(history, fmt.Sprint(label, " ", src0, " ", src1, " ", dst0, " ", dst1))
+				}))
+				got, err := transformChunks(r, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
+
+func TestReplaceHistory_Label(t *testing.T) {
+	history := NewReplaceHistory()
+	history.AddHistory(0, 1, 0, 1)
+	r := NewRedactor(&RedactOptions{Mask: MaskPlaceholder}, history)
+	if _, err := transformChunks(r, "alice@example.com 10.0.0.1", 100, 100); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	checkpoint, err := history.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	restored := NewReplaceHistory()
+	if err := restored.UnmarshalBinary(checkpoint); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	appended := NewReplaceHistory()
+	appended.Append(restored, 10, 20)
+
+	for _, h := range []*ReplaceHistory{history, restored, appended} {
+		var labels []string
+		for i := 0; i < h.Len(); i++ {
+			labels = append(labels, h.Label(i))
+		}
+
+		if g, e := fmt.Sprintf("%q", labels), `["" "EMAIL" "IPV4"]`; g != e {
+			t.Errorf("labels are expected %s but %s", e, g)
+		}
+	}
+}
+
+func TestNewRedactor_NilFunc(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("a panic is expected but not occured")
+		}
+	}()
+	NewRedactor(&RedactOptions{Mask: MaskFunc}, nil)
+}
+
+func TestRedactor_MaxLen(t *testing.T) {
+	r := NewRedactor(&RedactOptions{Mask: MaskPlaceholder, MaxLen: 5000}, nil)
+	input := strings.Repeat("a ", 3000) + "alice@example.com"
+	got, err := io.ReadAll(transform.NewReader(strings.NewReader(input), r))
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	if expected := strings.Repeat("a ", 3000) + "<EMAIL>"; string(got) != expected {
+		t.Error("unexpected output")
+	}
+}
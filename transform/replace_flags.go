@@ -0,0 +1,282 @@
+package transform
+
+import (
+	"bytes"
+
+	"golang.org/x/text/transform"
+)
+
+// ReplaceFlag represents options of matching of Replacer.
+type ReplaceFlag int
+
+const (
+	// IgnoreSpace makes a run of white spaces in old match
+	// a run of one or more white spaces in the input.
+	// White spaces are ' ', '\t', '\n', '\v', '\f' and '\r'.
+	IgnoreSpace ReplaceFlag = 1 << iota
+	// MatchLineStart makes old match only at the start of a line,
+	// which is the start of the stream or just after '\n'.
+	MatchLineStart
+	// MatchLineEnd makes old match only at the end of a line,
+	// which is the end of the stream or just before '\n' or '\r'.
+	MatchLineEnd
+	// MatchLine makes old match only an entire line.
+	// The line ending is not a part of the match.
+	MatchLine = MatchLineStart | MatchLineEnd
+)
+
+// NewReplacerFlags creates a new Replacer which replaces old to new with flags.
+// If flags is 0, it is the same as NewReplacer.
+//
+// If history is not nil, Replacer records histories of replacing.
+// Each history has the range of the input which is actually matched.
+func NewReplacerFlags(old, new []byte, flags ReplaceFlag, history HistorySink) *Replacer {
+	r := NewReplacer(old, new, history)
+	r.flags = flags
+	return r
+}
+
+// ReplaceAllFlags creates transform.Transformer which is chained Replacers with flags.
+// The Replacers replace by replacing rule which is indicated by ReplaceTable.
+func ReplaceAllFlags(t ReplaceTable, flags ReplaceFlag) transform.Transformer {
+	rs := make([]transform.Transformer, t.Len())
+	for i := range rs {
+		old, new := t.At(i)
+		rs[i] = NewReplacerFlags(old, new, flags, nil)
+	}
+	return transform.Chain(rs...)
+}
+
+func isSpace(b byte) bool {
+	switch b {
+	case ' ', '\t', '\n', '\v', '\f', '\r':
+		return true
+	}
+	return false
+}
+
+// flagsState is the progress of a partial match of a Replacer which has flags.
+type flagsState struct {
+	i     int  // the number of matched bytes of old
+	inRun bool // the input is in a run of white spaces which matches a run in old[:i]
+}
+
+// transformFlags is Transform of a Replacer which has flags.
+// Like transform, it keeps bytes of a partial match at the end of src in preSrc,
+// and it also keeps the progress of the match in preState,
+// so that a long run of white spaces can be consumed over Transform calls.
+func (r *Replacer) transformFlags(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { r.offDst += nDst }()
+
+	if len(r.preDst) > 0 {
+		nDst = copy(dst, r.preDst)
+		r.preDst = r.preDst[nDst:]
+		if len(r.preDst) > 0 {
+			return nDst, 0, transform.ErrShortDst
+		}
+		r.preDst = nil
+	}
+
+	if len(r.old) == 0 {
+		n := copy(dst[nDst:], src)
+		r.offSrc += n
+		if n < len(src) {
+			err = transform.ErrShortDst
+		}
+		return nDst + n, n, err
+	}
+
+	// resume the partial match
+	if r.preState.i > 0 {
+		n, state, ok, short := r.matchFlags(src, r.preState, false, atEOF)
+		switch {
+		case short:
+			r.preSrc = append(r.preSrc, src...)
+			r.preState = state
+			return nDst, len(src), nil
+		case ok:
+			m := len(r.preSrc)
+			addHistory(r.history, r.offSrc, r.offSrc+m+n, r.offDst+nDst, r.offDst+nDst+len(r.new))
+			r.offSrc += m
+			r.midLine = r.preSrc[m-1] != '\n'
+			r.preSrc, r.preState = nil, flagsState{}
+
+			w := copy(dst[nDst:], r.new)
+			nDst += w
+			nSrc = n
+			r.offSrc += n
+			if n > 0 {
+				r.midLine = src[n-1] != '\n'
+			}
+			if w < len(r.new) {
+				r.preDst = r.new[w:]
+				return nDst, nSrc, transform.ErrShortDst
+			}
+		default:
+			// transform preSrc again from its beginning
+			r.preState = flagsState{}
+		}
+	}
+
+	pre := r.preSrc
+	r.preSrc = nil
+	_src := src[nSrc:]
+	if len(pre) > 0 {
+		_src = make([]byte, len(pre)+len(src))
+		copy(_src, pre)
+		copy(_src[len(pre):], src)
+	}
+
+	nDst, m, err := r.transformFlagsSrc(dst, nDst, _src, atEOF)
+	if m < len(pre) {
+		// pre is not transformed completely because of ErrShortDst
+		r.preSrc = pre[m:]
+		return nDst, 0, err
+	}
+
+	return nDst, nSrc + m - len(pre), err
+}
+
+// transformFlagsSrc transforms src which begins at r.offSrc in the input to dst[nDst:].
+// It keeps bytes of a partial match at the end of src in preSrc,
+// and they are counted in nSrc as consumed bytes.
+func (r *Replacer) transformFlagsSrc(dst []byte, nDst int, src []byte, atEOF bool) (_, nSrc int, err error) {
+	defer func() {
+		r.offSrc += nSrc
+		if nSrc > 0 {
+			r.midLine = src[nSrc-1] != '\n'
+		}
+		nSrc += len(r.preSrc)
+	}()
+
+	for nSrc < len(src) {
+		bol := !r.midLine
+		if nSrc > 0 {
+			bol = src[nSrc-1] == '\n'
+		}
+
+		n, state, ok, short := r.matchFlags(src[nSrc:], flagsState{}, bol, atEOF)
+		if short {
+			// keep the partial match
+			r.preSrc = append([]byte(nil), src[nSrc:]...)
+			r.preState = state
+			return nDst, nSrc, nil
+		}
+
+		if !ok {
+			// copy until the next candidate of a match
+			end := nSrc + r.nextCandidate(src[nSrc:])
+			m := copy(dst[nDst:], src[nSrc:end])
+			nDst += m
+			nSrc += m
+			if nSrc < end {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			continue
+		}
+
+		addHistory(r.history, r.offSrc+nSrc, r.offSrc+nSrc+n, r.offDst+nDst, r.offDst+nDst+len(r.new))
+		m := copy(dst[nDst:], r.new)
+		nDst += m
+		nSrc += n
+		if m < len(r.new) {
+			r.preDst = r.new[m:]
+			return nDst, nSrc, transform.ErrShortDst
+		}
+	}
+
+	return nDst, nSrc, nil
+}
+
+// nextCandidate returns the index of the first byte in src
+// which can begin a match except src[0], or len(src).
+func (r *Replacer) nextCandidate(src []byte) int {
+	if r.flags&MatchLineStart != 0 {
+		if i := bytes.IndexByte(src, '\n'); i != -1 {
+			return i + 1
+		}
+		return len(src)
+	}
+
+	if r.flags&IgnoreSpace != 0 && isSpace(r.old[0]) {
+		for i := 1; i < len(src); i++ {
+			if isSpace(src[i]) {
+				return i
+			}
+		}
+		return len(src)
+	}
+
+	if len(src) > 0 {
+		if i := bytes.IndexByte(src[1:], r.old[0]); i != -1 {
+			return 1 + i
+		}
+	}
+	return len(src)
+}
+
+// matchFlags matches the beginning of src with old from the progress state.
+// It returns the length of the match in src and the new progress.
+// bol reports whether src begins at the start of a line, which is used only when nothing is matched yet.
+// If src does not begin with a match, ok is false.
+// If more bytes are needed to decide it, matchFlags reports short and n is len(src).
+func (r *Replacer) matchFlags(src []byte, state flagsState, bol, atEOF bool) (n int, _ flagsState, ok, short bool) {
+	if state.i == 0 && r.flags&MatchLineStart != 0 && !bol {
+		return 0, state, false, false
+	}
+
+	if state.inRun {
+		for n < len(src) && isSpace(src[n]) {
+			n++
+		}
+		if n == len(src) && !atEOF {
+			return n, state, false, true
+		}
+		state.inRun = false
+	}
+
+	ignoreSpace := r.flags&IgnoreSpace != 0
+	for state.i < len(r.old) {
+		if ignoreSpace && isSpace(r.old[state.i]) {
+			if n == len(src) {
+				return n, state, false, !atEOF
+			}
+			if !isSpace(src[n]) {
+				return 0, state, false, false
+			}
+
+			// a run of white spaces matches as long as possible
+			for state.i < len(r.old) && isSpace(r.old[state.i]) {
+				state.i++
+			}
+			for n < len(src) && isSpace(src[n]) {
+				n++
+			}
+			if n == len(src) && !atEOF {
+				state.inRun = true
+				return n, state, false, true
+			}
+			continue
+		}
+
+		if n == len(src) {
+			return n, state, false, !atEOF
+		}
+		if src[n] != r.old[state.i] {
+			return 0, state, false, false
+		}
+		state.i++
+		n++
+	}
+
+	if r.flags&MatchLineEnd != 0 {
+		switch {
+		case n == len(src) && !atEOF:
+			return n, state, false, true
+		case n < len(src) && src[n] != '\n' && src[n] != '\r':
+			return 0, state, false, false
+		}
+	}
+
+	return n, state, true, false
+}
@@ -0,0 +1,166 @@
+package transform_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestReplacerFlags_Transform(t *testing.T) {
+	cases := []struct {
+		old, new string
+		flags    ReplaceFlag
+		input    string
+
+		expected string
+		history  []HistoryEntry
+	}{
+		{
+			old:      "{\n\tf()\n}",
+			new:      "{}",
+			flags:    IgnoreSpace,
+			input:    "x   {\n    f()\n}\n",
+			expected: "x   {}\n",
+			history:  []HistoryEntry{{4, 15, 4, 6}},
+		},
+		{
+			old:      "a b",
+			new:      "X",
+			flags:    IgnoreSpace,
+			input:    "ab a b a\t\t\nb a  b",
+			expected: "ab X X X",
+			history:  []HistoryEntry{{3, 6, 3, 4}, {7, 12, 5, 6}, {13, 17, 7, 8}},
+		},
+		{
+			// a white space in old matches the whole run of white spaces
+			old:      "a ",
+			new:      "X",
+			flags:    IgnoreSpace,
+			input:    "a    b a",
+			expected: "Xb a",
+			history:  []HistoryEntry{{0, 5, 0, 1}},
+		},
+		{
+			old:      " a",
+			new:      "X",
+			flags:    IgnoreSpace,
+			input:    "b  a a",
+			expected: "bXX",
+			history:  []HistoryEntry{{1, 4, 1, 2}, {4, 6, 2, 3}},
+		},
+		{
+			old:      "## ",
+			new:      "### ",
+			flags:    MatchLineStart,
+			input:    "## a ## b\n## c\n",
+			expected: "### a ## b\n### c\n",
+			history:  []HistoryEntry{{0, 3, 0, 4}, {10, 13, 11, 15}},
+		},
+		{
+			old:      ";",
+			new:      "",
+			flags:    MatchLineEnd,
+			input:    "a; b;\nc;\r\nd;",
+			expected: "a; b\nc\r\nd",
+			history:  []HistoryEntry{{4, 5, 4, 4}, {7, 8, 6, 6}, {11, 12, 9, 9}},
+		},
+		{
+			old:      "old",
+			new:      "new",
+			flags:    MatchLine,
+			input:    "old\nold old\n old\nold",
+			expected: "new\nold old\n old\nnew",
+			history:  []HistoryEntry{{0, 3, 0, 3}, {17, 20, 17, 20}},
+		},
+		{
+			old:      "a b",
+			new:      "X",
+			flags:    MatchLine | IgnoreSpace,
+			input:    "a  b\nxa b\na\tb",
+			expected: "X\nxa b\nX",
+			history:  []HistoryEntry{{0, 4, 0, 1}, {10, 13, 7, 8}},
+		},
+		{
+			old:      "a b",
+			new:      "X",
+			input:    "a  b a b",
+			expected: "a  b X",
+			history:  []HistoryEntry{{5, 8, 5, 6}},
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {12, 1}, {13, 4}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				r := NewReplacerFlags([]byte(c.old), []byte(c.new), c.flags, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(r, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
+
+func TestReplacerFlags_LongSpaces(t *testing.T) {
+	spaces := strings.Repeat(" ", 5000)
+	cases := []struct {
+		flags    ReplaceFlag
+		input    string
+		expected string
+	}{
+		{IgnoreSpace, "a" + spaces + "b", "X"},
+		{IgnoreSpace, "a" + spaces + "c", "a" + spaces + "c"},
+		{IgnoreSpace, "a" + spaces + "a" + spaces + "b!", "a" + spaces + "X!"},
+		{IgnoreSpace | MatchLineEnd, "a" + spaces + "b\nc", "X\nc"},
+	}
+
+	for i, c := range cases {
+		r := NewReplacerFlags([]byte("a b"), []byte("X"), c.flags, nil)
+		got, err := io.ReadAll(transform.NewReader(strings.NewReader(c.input), r))
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+
+		if string(got) != c.expected {
+			t.Errorf("%d: the output is expected %q but %q", i, c.expected, got)
+		}
+	}
+}
+
+func TestReplacerFlags_MarshalBinary(t *testing.T) {
+	r := NewReplacerFlags([]byte("a b"), []byte("X"), IgnoreSpace, nil)
+	out := transformChunk(t, r, []byte("x a   "), false)
+
+	checkpoint, err := r.MarshalBinary()
+	if err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+
+	r = NewReplacerFlags([]byte("a b"), []byte("X"), IgnoreSpace, nil)
+	if err := r.UnmarshalBinary(checkpoint); err != nil {
+		t.Fatal("unexpected error:", err)
+	}
+	out = append(out, transformChunk(t, r, []byte("  b"), true)...)
+
+	if got, expected := string(out), "x X"; got != expected {
+		t.Errorf("the output is expected %q but %q", expected, got)
+	}
+}
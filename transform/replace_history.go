@@ -1,22 +1,83 @@
 package transform
 
+import (
+	"fmt"
+	"sync"
+)
+
 // ReplaceHistory represents histories of replacing with Replacer.
 type ReplaceHistory struct {
+	mu         *sync.Mutex // nil if the history is not synchronized
 	src0, src1 []int
 	dst0, dst1 []int
+	labels     []string // nil until a labeled history is recorded
 }
 
 // NewReplaceHistory creates a new ReplaceHistory.
+// The history is not safe for concurrent use.
+// Use NewSyncReplaceHistory or merge per-stream histories with Append instead.
 func NewReplaceHistory() *ReplaceHistory {
 	return &ReplaceHistory{}
 }
 
+// NewSyncReplaceHistory creates a new ReplaceHistory which is safe for concurrent use
+// by multiple goroutines.
+// Histories of streams which share the history are recorded in the order of replacing.
+func NewSyncReplaceHistory() *ReplaceHistory {
+	return &ReplaceHistory{mu: new(sync.Mutex)}
+}
+
+func (h *ReplaceHistory) lock() {
+	if h.mu != nil {
+		h.mu.Lock()
+	}
+}
+
+func (h *ReplaceHistory) unlock() {
+	if h.mu != nil {
+		h.mu.Unlock()
+	}
+}
+
+// AddHistory implements HistorySink.AddHistory.
+// This method can call with a nil receiver.
+func (h *ReplaceHistory) AddHistory(src0, src1, dst0, dst1 int) {
+	h.add(src0, src1, dst0, dst1)
+}
+
+// AddLabeledHistory implements LabeledHistorySink.AddLabeledHistory.
+// This method can call with a nil receiver.
+func (h *ReplaceHistory) AddLabeledHistory(label string, src0, src1, dst0, dst1 int) {
+	// ignore receiver is nil
+	if h == nil {
+		return
+	}
+
+	h.lock()
+	defer h.unlock()
+	h.addLabeled(label, src0, src1, dst0, dst1)
+}
+
 func (h *ReplaceHistory) add(src0, src1, dst0, dst1 int) {
 	// ignore receiver is nil
 	if h == nil {
 		return
 	}
 
+	h.lock()
+	defer h.unlock()
+	h.addLabeled("", src0, src1, dst0, dst1)
+}
+
+// addLabeled records a history without locking.
+func (h *ReplaceHistory) addLabeled(label string, src0, src1, dst0, dst1 int) {
+	if h.labels == nil && label != "" {
+		h.labels = make([]string, len(h.src0), cap(h.src0))
+	}
+	if h.labels != nil {
+		h.labels = append(h.labels, label)
+	}
+
 	h.src0 = append(h.src0, src0)
 	h.src1 = append(h.src1, src1)
 	h.dst0 = append(h.dst0, dst0)
@@ -27,12 +88,16 @@ func (h *ReplaceHistory) add(src0, src1, dst0, dst1 int) {
 // This method can call with a nil receiver.
 // The arguments of f represent range of replacing, from src[src0:src1] to dst[dst0:dst1].
 // if f returns false Iterate will stop the iteration.
+// f must not record histories into h.
 func (h *ReplaceHistory) Iterate(f func(src0, src1, dst0, dst1 int) bool) {
 	// ignore receiver is nil
 	if h == nil {
 		return
 	}
 
+	h.lock()
+	defer h.unlock()
+
 	for i := range h.src0 {
 		if !f(h.src0[i], h.src1[i], h.dst0[i], h.dst1[i]) {
 			break
@@ -41,8 +106,84 @@ func (h *ReplaceHistory) Iterate(f func(src0, src1, dst0, dst1 int) bool) {
 }
 
 // At returns a history of given index.
+// This method can call with a nil receiver, which has no histories,
+// so it panics as index out of range.
 func (h *ReplaceHistory) At(index int) (src0, src1, dst0, dst1 int) {
+	if h == nil {
+		panic(fmt.Sprintf("transform: index out of range [%d] with length 0", index))
+	}
+
+	h.lock()
+	defer h.unlock()
 	return h.src0[index], h.src1[index], h.dst0[index], h.dst1[index]
 }
 
+// Label returns the label of a history of given index,
+// such as the name of a detector which found the replaced data.
+// It returns an empty string if the history does not have a label.
+// Like At, it panics if index is out of range, including with a nil receiver.
+func (h *ReplaceHistory) Label(index int) string {
+	if h == nil {
+		panic(fmt.Sprintf("transform: index out of range [%d] with length 0", index))
+	}
+
+	h.lock()
+	defer h.unlock()
+	if index < 0 || index >= len(h.src0) {
+		panic(fmt.Sprintf("transform: index out of range [%d] with length %d", index, len(h.src0)))
+	}
+	if h.labels == nil {
+		return ""
+	}
+	return h.labels[index]
+}
+
+// Len returns the number of histories.
+// This method can call with a nil receiver.
+func (h *ReplaceHistory) Len() int {
+	// ignore receiver is nil
+	if h == nil {
+		return 0
+	}
+
+	h.lock()
+	defer h.unlock()
+	return len(h.src0)
+}
+
+// Append appends histories of other to h.
+// The ranges of appended histories are shifted by srcOffset and dstOffset.
+// It is useful to merge histories of streams which are processed in parallel,
+// such as parts of a file which are concatenated after replacing.
+// This method can call with a nil receiver and a nil other.
+func (h *ReplaceHistory) Append(other *ReplaceHistory, srcOffset, dstOffset int) {
+	// ignore receiver is nil
+	if h == nil {
+		return
+	}
 
+	// copy other first to avoid a deadlock when h == other
+	var src0, src1, dst0, dst1 []int
+	var labels []string
+	if other != nil {
+		other.lock()
+		for i := range other.src0 {
+			src0 = append(src0, other.src0[i]+srcOffset)
+			src1 = append(src1, other.src1[i]+srcOffset)
+			dst0 = append(dst0, other.dst0[i]+dstOffset)
+			dst1 = append(dst1, other.dst1[i]+dstOffset)
+		}
+		labels = append(labels, other.labels...)
+		other.unlock()
+	}
+
+	h.lock()
+	defer h.unlock()
+	for i := range src0 {
+		var label string
+		if labels != nil {
+			label = labels[i]
+		}
+		h.addLabeled(label, src0[i], src1[i], dst0[i], dst1[i])
+	}
+}
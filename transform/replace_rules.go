@@ -0,0 +1,121 @@
+package transform
+
+import (
+	"bytes"
+
+	"golang.org/x/text/transform"
+)
+
+// ReplaceRules is a compiled and immutable set of replacing rules.
+// Because a Replacer has the state of a stream, it cannot be shared between goroutines.
+// On the other hand ReplaceRules is safe for concurrent use by multiple goroutines
+// and it cheaply creates a transformer for each stream.
+type ReplaceRules struct {
+	old, new [][]byte
+}
+
+// CompileReplace compiles a replacing rule which replaces old to new.
+func CompileReplace(old, new []byte) *ReplaceRules {
+	return &ReplaceRules{
+		old: [][]byte{bytes.Clone(old)},
+		new: [][]byte{bytes.Clone(new)},
+	}
+}
+
+// CompileReplaceTable compiles replacing rules which are indicated by ReplaceTable.
+// The rules are copied, so modifying t after compiling does not affect the ReplaceRules.
+func CompileReplaceTable(t ReplaceTable) *ReplaceRules {
+	rs := &ReplaceRules{
+		old: make([][]byte, t.Len()),
+		new: make([][]byte, t.Len()),
+	}
+	for i := range rs.old {
+		old, new := t.At(i)
+		rs.old[i], rs.new[i] = bytes.Clone(old), bytes.Clone(new)
+	}
+	return rs
+}
+
+// At implements ReplaceTable.At.
+// The returned bytes must not be modified.
+func (rs *ReplaceRules) At(i int) (old, new []byte) {
+	return rs.old[i], rs.new[i]
+}
+
+// Len implements ReplaceTable.Len.
+func (rs *ReplaceRules) Len() int {
+	return len(rs.old)
+}
+
+// Replacers creates Replacers for a stream, one per rule.
+// If history is not nil, the Replacers record histories of replacing.
+func (rs *ReplaceRules) Replacers(history HistorySink) []*Replacer {
+	replacers := make([]*Replacer, len(rs.old))
+	for i := range replacers {
+		replacers[i] = NewReplacer(rs.old[i], rs.new[i], history)
+	}
+	return replacers
+}
+
+// NewTransformer creates a new transform.Transformer for a stream.
+// If the ReplaceRules has only one rule, it returns a *Replacer,
+// otherwise it returns chained Replacers like ReplaceAll.
+//
+// If history is not nil, the Replacers record histories of replacing.
+// When the rules are chained, each history is recorded by one of the Replacers,
+// so its src range is on the input of the rule and its dst range is on the output of the rule.
+// To record histories of streams in parallel, give each stream its own history
+// and merge them with ReplaceHistory.Append, or use NewSyncReplaceHistory.
+func (rs *ReplaceRules) NewTransformer(history HistorySink) transform.Transformer {
+	replacers := rs.Replacers(history)
+	if len(replacers) == 1 {
+		return replacers[0]
+	}
+
+	ts := make([]transform.Transformer, len(replacers))
+	for i := range replacers {
+		ts[i] = replacers[i]
+	}
+	return transform.Chain(ts...)
+}
+
+// index returns the index of the first match of the rules in b and the index of the matched rule.
+// If some rules match at the same index, the former rule has priority.
+// Rules whose old is empty never match.
+// If no rule matches, index returns -1, -1.
+func (rs *ReplaceRules) index(b []byte) (i, rule int) {
+	i, rule = -1, -1
+	for j, old := range rs.old {
+		if len(old) == 0 {
+			continue
+		}
+
+		end := len(b)
+		if i != -1 {
+			// a match which starts after i is not needed
+			end = i + len(old) - 1
+			if end > len(b) {
+				end = len(b)
+			}
+		}
+
+		if k := bytes.Index(b[:end], old); k != -1 && (i == -1 || k < i) {
+			i, rule = k, j
+		}
+	}
+	return i, rule
+}
+
+// replaceAll replaces all matches of the rules in b from left to right
+// and calls f with the range of each match and the index of the matched rule.
+func (rs *ReplaceRules) replaceAll(b []byte, f func(start, end, rule int)) {
+	for pos := 0; pos < len(b); {
+		i, rule := rs.index(b[pos:])
+		if i == -1 {
+			return
+		}
+		end := pos + i + len(rs.old[rule])
+		f(pos+i, end, rule)
+		pos = end
+	}
+}
@@ -0,0 +1,113 @@
+package transform_test
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func ExampleReplaceRules() {
+	rules := CompileReplaceTable(ReplaceStringTable{
+		"Hello", "Hi",
+		"World", "Gophers",
+	})
+
+	// NewTransformer can be called from multiple goroutines.
+	r := transform.NewReader(strings.NewReader("Hello, World"), rules.NewTransformer(nil))
+	io.Copy(os.Stdout, r)
+	// Output: Hi, Gophers
+}
+
+func TestReplaceRules_Parallel(t *testing.T) {
+	t.Parallel()
+
+	table := ReplaceStringTable{"abc", "ABCDE"}
+	rules := CompileReplaceTable(table)
+	// modifying the table must not affect the compiled rules
+	table[1] = "X"
+
+	const (
+		n     = 10
+		input = "xabcx"
+	)
+	syncHistory := NewSyncReplaceHistory()
+	histories := make([]*ReplaceHistory, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		histories[i] = NewReplaceHistory()
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for _, h := range []*ReplaceHistory{histories[i], syncHistory} {
+				got, _, err := transform.String(rules.NewTransformer(h), input)
+				if err != nil {
+					t.Error("unexpected error:", err)
+					return
+				}
+				if got != "xABCDEx" {
+					t.Errorf("the output is expected %q but %q", "xABCDEx", got)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if syncHistory.Len() != n {
+		t.Errorf("the length of synchronized history is expected %d but %d", n, syncHistory.Len())
+	}
+
+	// merge per-stream histories as if outputs were concatenated
+	merged := NewReplaceHistory()
+	for i, h := range histories {
+		merged.Append(h, i*len(input), i*len("xABCDEx"))
+	}
+
+	if merged.Len() != n {
+		t.Fatalf("the length of merged history is expected %d but %d", n, merged.Len())
+	}
+
+	for i := 0; i < n; i++ {
+		src0, src1, dst0, dst1 := merged.At(i)
+		if src0 != i*5+1 || src1 != i*5+4 || dst0 != i*7+1 || dst1 != i*7+6 {
+			t.Errorf("unexpected merged history[%d] (%d, %d, %d, %d)", i, src0, src1, dst0, dst1)
+		}
+	}
+}
+
+func TestReplaceHistory_Nil(t *testing.T) {
+	var h *ReplaceHistory
+	h.AddHistory(0, 1, 0, 1)
+	h.Append(NewReplaceHistory(), 0, 0)
+	if h.Len() != 0 {
+		t.Errorf("the length is expected 0 but %d", h.Len())
+	}
+
+	other := NewReplaceHistory()
+	other.Append(nil, 0, 0)
+	if other.Len() != 0 {
+		t.Errorf("the length is expected 0 but %d", other.Len())
+	}
+
+	for name, f := range map[string]func(){
+		"At":    func() { h.At(0) },
+		"Label": func() { h.Label(0) },
+		// out of range of a history without labels
+		"Label of other": func() { other.Label(5) },
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(fmt.Sprint(r), "index out of range") {
+					t.Errorf("%s: a panic of index out of range is expected but %v", name, r)
+				}
+			}()
+			f()
+		}()
+	}
+}
@@ -0,0 +1,17 @@
+module github.com/tenntenn/text/transform/replacecheck
+
+go 1.23.8
+
+require (
+	github.com/tenntenn/text/transform v0.0.0-20261018213411-1223e96d0f3d
+	golang.org/x/tools v0.31.0
+)
+
+require (
+	golang.org/x/mod v0.24.0 // indirect
+	golang.org/x/sync v0.12.0 // indirect
+	golang.org/x/text v0.23.0 // indirect
+)
+
+// use the transform module in this repository while developing
+replace github.com/tenntenn/text/transform => ../
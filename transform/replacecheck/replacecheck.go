@@ -0,0 +1,73 @@
+// Package replacecheck provides a constructor of analysis.Analyzer
+// which reports texts to be replaced in comments and string literals.
+//
+// The analyzer reuses transform.ReplaceGoSource,
+// so it finds the same matches as replacing with transform.GoReplacer.
+package replacecheck
+
+import (
+	"fmt"
+
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/tenntenn/text/transform"
+)
+
+// NewAnalyzer creates a new analysis.Analyzer which reports every match of
+// the replacing rules in comments and string literals.
+// Each diagnostic has a SuggestedFix which replaces the matched text to new of the rule.
+//
+// The rules are indicated by ReplaceTable, such as a list of deprecated names or banned words.
+func NewAnalyzer(name string, t transform.ReplaceTable) *analysis.Analyzer {
+	rules := transform.CompileReplaceTable(t)
+	return &analysis.Analyzer{
+		Name: name,
+		Doc:  fmt.Sprintf("%s reports texts to be replaced in comments and string literals", name),
+		Run: func(pass *analysis.Pass) (any, error) {
+			return nil, run(pass, rules)
+		},
+	}
+}
+
+func run(pass *analysis.Pass, rules *transform.ReplaceRules) error {
+	for _, f := range pass.Files {
+		tf := pass.Fset.File(f.FileStart)
+		if tf == nil {
+			continue
+		}
+
+		src, err := pass.ReadFile(tf.Name())
+		if err != nil {
+			return err
+		}
+
+		// the file may be generated by cgo
+		if len(src) != tf.Size() {
+			continue
+		}
+
+		_, rs, err := transform.ReplaceGoSource(tf.Name(), src, rules, transform.GoComment|transform.GoString)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range rs {
+			pos, end := tf.Pos(r.Pos.Offset), tf.Pos(r.End.Offset)
+			pass.Report(analysis.Diagnostic{
+				Pos:     pos,
+				End:     end,
+				Message: fmt.Sprintf("%q should be replaced with %q", r.Old, r.New),
+				SuggestedFixes: []analysis.SuggestedFix{{
+					Message: fmt.Sprintf("Replace %q with %q", r.Old, r.New),
+					TextEdits: []analysis.TextEdit{{
+						Pos:     pos,
+						End:     end,
+						NewText: []byte(r.New),
+					}},
+				}},
+			})
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package replacecheck_test
+
+import (
+	"testing"
+
+	"golang.org/x/tools/go/analysis/analysistest"
+
+	"github.com/tenntenn/text/transform"
+	"github.com/tenntenn/text/transform/replacecheck"
+)
+
+func TestAnalyzer(t *testing.T) {
+	a := replacecheck.NewAnalyzer("termcheck", transform.ReplaceStringTable{
+		"blacklist", "denylist",
+		"whitelist", "allowlist",
+	})
+	testdata := analysistest.TestData()
+	analysistest.RunWithSuggestedFixes(t, testdata, a, "a")
+}
@@ -0,0 +1,9 @@
+package a
+
+// blacklist is a list of denied hosts. // want `"black.ist" should be replaced with "denylist"`
+var blacklist = []string{"example.com"}
+
+func f() {
+	println("whitelist") // want `"white.ist" should be replaced with "allowlist"`
+	println(blacklist)
+}
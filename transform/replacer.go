@@ -11,9 +11,12 @@ import (
 // It implements transform.Transformer.
 type Replacer struct {
 	old, new []byte
-	history  *ReplaceHistory
+	flags    ReplaceFlag
+	history  HistorySink
 	preDst   []byte
-	preSrc   []byte // preSrc always points subslice of old.
+	preSrc   []byte     // preSrc points subslice of old, or bytes of a partial match with flags.
+	preState flagsState // progress of the partial match in preSrc with flags.
+	midLine  bool       // the last transformed byte is not '\n', used with MatchLineStart.
 	// offDst and offSrc is the length of transformed bytes until the current Transform call.
 	offDst int
 	offSrc int
@@ -26,11 +29,12 @@ var _ transform.Transformer = (*Replacer)(nil)
 // if old is empty the Replacer does not replace and just copy src to dst.
 //
 // If history is not nil, Replacer records histories of replacing.
-func NewReplacer(old, new []byte, history *ReplaceHistory) *Replacer {
+// history is typically a *ReplaceHistory, but any HistorySink can receive histories as they happen.
+func NewReplacer(old, new []byte, history HistorySink) *Replacer {
 	return &Replacer{
 		new:     new,
 		old:     old,
-		history: history,
+		history: historySink(history),
 	}
 }
 
@@ -38,6 +42,8 @@ func NewReplacer(old, new []byte, history *ReplaceHistory) *Replacer {
 func (r *Replacer) Reset() {
 	r.preDst = nil
 	r.preSrc = nil
+	r.preState = flagsState{}
+	r.midLine = false
 	r.offDst = 0
 	r.offSrc = 0
 }
@@ -52,6 +58,9 @@ func (r *Replacer) Reset() {
 // If Replacer remained boundary bytes, nSrc will be less than len(src)
 // and returns transform.ErrShortSrc.
 func (r *Replacer) Transform(dst, src []byte, atEOF bool) (int, int, error) {
+	if r.flags != 0 {
+		return r.transformFlags(dst, src, atEOF)
+	}
 
 	_src := src
 	if len(r.preSrc) > 0 {
@@ -131,7 +140,7 @@ func (r *Replacer) transform(dst, src []byte, atEOF bool) (nDst, nSrc int, preSr
 		}
 
 		// Copy new
-		r.history.add(r.offSrc+nSrc, r.offSrc+nSrc+len(r.old), r.offDst+nDst, r.offDst+nDst+len(r.new))
+		addHistory(r.history, r.offSrc+nSrc, r.offSrc+nSrc+len(r.old), r.offDst+nDst, r.offDst+nDst+len(r.new))
 		n = copy(dst[nDst:], r.new)
 		nDst += n
 		nSrc += len(r.old)
@@ -0,0 +1,114 @@
+package transform
+
+import (
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+)
+
+// RuneClassReplacer replaces runes which belong to a class such as unicode.Cc.
+// It implements transform.Transformer.
+//
+// Invalid UTF-8 bytes do not belong to any class and are copied as they are.
+type RuneClassReplacer struct {
+	table *unicode.RangeTable
+	f     func(r rune) string
+	// reject returns an error for a rune in the class if it is not nil.
+	// offset is the offset of the rune in the input.
+	reject func(r rune, offset int) error
+	w      streamWriter
+}
+
+var _ transform.Transformer = (*RuneClassReplacer)(nil)
+
+// NewRuneClassReplacer creates a new RuneClassReplacer which replaces each rune in table to f(r).
+// If f is nil, the runes in table are deleted.
+//
+// If history is not nil, RuneClassReplacer records histories of replacing.
+// Runes for which f returns themselves are not recorded.
+func NewRuneClassReplacer(table *unicode.RangeTable, f func(r rune) string, history HistorySink) *RuneClassReplacer {
+	return &RuneClassReplacer{
+		table: table,
+		f:     f,
+		w:     newStreamWriter(history),
+	}
+}
+
+// ReplaceRuneClass returns a RuneClassReplacer which replaces each rune in table to new.
+func ReplaceRuneClass(table *unicode.RangeTable, new string) *RuneClassReplacer {
+	return NewRuneClassReplacer(table, func(rune) string { return new }, nil)
+}
+
+// DeleteRuneClass returns a RuneClassReplacer which deletes runes in table.
+func DeleteRuneClass(table *unicode.RangeTable) *RuneClassReplacer {
+	return NewRuneClassReplacer(table, nil, nil)
+}
+
+// Reset implements transform.Transformer.Reset.
+func (r *RuneClassReplacer) Reset() {
+	r.w.reset()
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When src ends with an incomplete rune and atEOF is false,
+// the RuneClassReplacer stops to transform and returns transform.ErrShortSrc.
+func (r *RuneClassReplacer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { r.w.done(nDst, nSrc) }()
+
+	nDst, err = r.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	for nSrc < len(src) {
+		// copy ASCII bytes which are not in the class at once
+		end := nSrc
+		for end < len(src) && src[end] < utf8.RuneSelf && !unicode.Is(r.table, rune(src[end])) {
+			end++
+		}
+		if end > nSrc {
+			if nDst, nSrc, err = r.w.copy(dst, src, nDst, nSrc, end); err != nil {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		if !atEOF && !utf8.FullRune(src[nSrc:]) {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+
+		c, n := utf8.DecodeRune(src[nSrc:])
+		invalid := c == utf8.RuneError && n == 1
+		if invalid || !unicode.Is(r.table, c) {
+			nDst, nSrc, err = r.w.copy(dst, src, nDst, nSrc, nSrc+n)
+			if err != nil {
+				return nDst, nSrc, err
+			}
+			continue
+		}
+
+		if r.reject != nil {
+			return nDst, nSrc, r.reject(c, r.w.offSrc+nSrc)
+		}
+
+		var new string
+		if r.f != nil {
+			new = r.f(c)
+		}
+
+		if new == string(c) {
+			nDst, nSrc, err = r.w.copy(dst, src, nDst, nSrc, nSrc+n)
+		} else {
+			nDst, err = r.w.replace(dst, nDst, nSrc, nSrc+n, []byte(new))
+			nSrc += n
+		}
+
+		if err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	return nDst, nSrc, nil
+}
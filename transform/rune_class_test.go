@@ -0,0 +1,76 @@
+package transform_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestRuneClassReplacer_Transform(t *testing.T) {
+	cases := []struct {
+		table *unicode.RangeTable
+		f     func(r rune) string
+		input string
+
+		expected string
+		history  []HistoryEntry
+	}{
+		{
+			table:    unicode.Zs,
+			f:        func(rune) string { return " " },
+			input:    "a　b c d",
+			expected: "a b c d",
+			history:  []HistoryEntry{{1, 4, 1, 2}, {5, 7, 3, 4}},
+		},
+		{
+			table:    unicode.Cc,
+			input:    "a\x00b\tc\u0085\n",
+			expected: "abc",
+			history:  []HistoryEntry{{1, 2, 1, 1}, {3, 4, 2, 2}, {5, 7, 3, 3}, {7, 8, 3, 3}},
+		},
+		{
+			table: unicode.Han,
+			f: func(r rune) string {
+				return fmt.Sprintf("<%U>", r)
+			},
+			input:    "漢a字\xff",
+			expected: "<U+6F22>a<U+5B57>\xff",
+			history:  []HistoryEntry{{0, 3, 0, 8}, {4, 7, 9, 17}},
+		},
+		{
+			// runes which f returns themselves are not recorded
+			table:    unicode.Latin,
+			f:        func(r rune) string { return strings.ToUpper(string(r)) },
+			input:    "aBé",
+			expected: "ABÉ",
+			history:  []HistoryEntry{{0, 1, 0, 1}, {2, 4, 2, 4}},
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {3, 1}, {4, 3}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				r := NewRuneClassReplacer(c.table, c.f, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(r, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
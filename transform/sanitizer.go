@@ -0,0 +1,58 @@
+package transform
+
+import (
+	"errors"
+	"fmt"
+	"unicode"
+)
+
+// ErrUnsafeRune is returned by a sanitizer when it finds an unsafe rune
+// and the mode is SanitizeError.
+var ErrUnsafeRune = errors.New("transform: unsafe rune")
+
+// SanitizeMode represents how a sanitizer treats unsafe runes.
+type SanitizeMode int
+
+const (
+	// SanitizeRemove removes unsafe runes.
+	SanitizeRemove SanitizeMode = iota
+	// SanitizeEscape replaces unsafe runes with visible escapes such as `\u202e`.
+	// Runes beyond the BMP are escaped such as `\U000e0001`.
+	SanitizeEscape
+	// SanitizeError returns an error which wraps ErrUnsafeRune on the first unsafe rune.
+	SanitizeError
+)
+
+// NewSanitizer creates a new RuneClassReplacer which defends against Trojan Source attacks
+// by sanitizing unsafe runes in table.
+// If table is nil, unicode.Cf is used, which contains bidirectional controls such as U+202E,
+// zero-width characters such as U+200B and other invisible format runes.
+// Note that unicode.Cf also contains U+200D (ZERO WIDTH JOINER) which is used in emoji sequences.
+//
+// If history is not nil, the sanitizer records histories of removed or escaped runes,
+// which can be used as a report.
+func NewSanitizer(mode SanitizeMode, table *unicode.RangeTable, history HistorySink) *RuneClassReplacer {
+	if table == nil {
+		table = unicode.Cf
+	}
+
+	r := NewRuneClassReplacer(table, nil, history)
+	switch mode {
+	case SanitizeEscape:
+		r.f = escapeRune
+	case SanitizeError:
+		r.reject = func(c rune, offset int) error {
+			return fmt.Errorf("%w: %U at offset %d", ErrUnsafeRune, c, offset)
+		}
+	}
+
+	return r
+}
+
+// escapeRune returns the escape of r in Go syntax.
+func escapeRune(r rune) string {
+	if r > 0xFFFF {
+		return fmt.Sprintf(`\U%08x`, r)
+	}
+	return fmt.Sprintf(`\u%04x`, r)
+}
@@ -0,0 +1,82 @@
+package transform_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"unicode"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestSanitizer(t *testing.T) {
+	cases := []struct {
+		mode  SanitizeMode
+		table *unicode.RangeTable
+		input string
+
+		expected string
+		history  []HistoryEntry
+		err      error
+	}{
+		{
+			mode:     SanitizeRemove,
+			input:    "if a\u202e {\u2066x\u2069}\u200b",
+			expected: "if a {x}",
+			history:  []HistoryEntry{{4, 7, 4, 4}, {9, 12, 6, 6}, {13, 16, 7, 7}, {17, 20, 8, 8}},
+		},
+		{
+			mode:     SanitizeEscape,
+			input:    "a\u202eb\U000e0001",
+			expected: `a\u202eb\U000e0001`,
+			history:  []HistoryEntry{{1, 4, 1, 7}, {5, 9, 8, 18}},
+		},
+		{
+			mode:     SanitizeEscape,
+			table:    unicode.Bidi_Control,
+			input:    "a\u200b\u061c",
+			expected: "a\u200b\\u061c",
+			history:  []HistoryEntry{{4, 6, 4, 10}},
+		},
+		{
+			mode:  SanitizeError,
+			input: "abc\u202e",
+			err:   ErrUnsafeRune,
+		},
+		{
+			mode:     SanitizeError,
+			input:    "safe text",
+			expected: "safe text",
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {4, 1}, {5, 3}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				s := NewSanitizer(c.mode, c.table, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(s, c.input, size[0], size[1])
+				switch {
+				case c.err != nil:
+					if !errors.Is(err, c.err) {
+						t.Fatalf("the error is expected %v but %v", c.err, err)
+					}
+					return
+				case err != nil:
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
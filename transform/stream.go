@@ -0,0 +1,133 @@
+package transform
+
+import (
+	"bytes"
+
+	"golang.org/x/text/transform"
+)
+
+// maxLookahead is the maximum number of bytes which transformers can look ahead.
+// Bytes to look ahead must fit in the source buffer of transform.Reader and transform.Writer.
+const maxLookahead = 4096
+
+// streamWriter writes outputs of a transformer to dst of Transform
+// and records histories of replacing.
+// Like Replacer, bytes which cannot be written to dst are kept
+// until the next Transform call.
+type streamWriter struct {
+	history HistorySink
+	preDst  []byte
+	// rest is the number of bytes of a token which is partially copied.
+	// They are copied by copyRest in the next Transform call.
+	rest int
+	// offDst and offSrc is the length of transformed bytes until the current Transform call.
+	offDst int
+	offSrc int
+}
+
+func newStreamWriter(history HistorySink) streamWriter {
+	return streamWriter{history: historySink(history)}
+}
+
+func (w *streamWriter) reset() {
+	w.preDst = nil
+	w.rest = 0
+	w.offDst = 0
+	w.offSrc = 0
+}
+
+// flush writes the kept bytes to dst.
+// It returns transform.ErrShortDst if dst is too short to write all of them.
+func (w *streamWriter) flush(dst []byte) (int, error) {
+	if len(w.preDst) == 0 {
+		return 0, nil
+	}
+
+	n := copy(dst, w.preDst)
+	w.preDst = w.preDst[n:]
+	if len(w.preDst) > 0 {
+		return n, transform.ErrShortDst
+	}
+	w.preDst = nil
+
+	return n, nil
+}
+
+// copy copies src[nSrc:end] to dst[nDst:] and returns new nDst and nSrc.
+// If dst is too short, it returns transform.ErrShortDst.
+func (w *streamWriter) copy(dst, src []byte, nDst, nSrc, end int) (int, int, error) {
+	n := copy(dst[nDst:], src[nSrc:end])
+	if n < end-nSrc {
+		return nDst + n, nSrc + n, transform.ErrShortDst
+	}
+	return nDst + n, nSrc + n, nil
+}
+
+// copyToken copies src[nSrc:nSrc+n] to dst[nDst:] as a token such as a delimiter.
+// If dst is too short, the rest of the token is copied by copyRest in the next Transform call,
+// so a transformer can treat the token as if it were copied at once.
+func (w *streamWriter) copyToken(dst, src []byte, nDst, nSrc, n int) (int, int, error) {
+	start := nSrc
+	nDst, nSrc, err := w.copy(dst, src, nDst, nSrc, nSrc+n)
+	if err != nil {
+		w.rest = n - (nSrc - start)
+	}
+	return nDst, nSrc, err
+}
+
+// copyRest copies the rest of a token which is partially copied by copyToken.
+func (w *streamWriter) copyRest(dst, src []byte, nDst int) (int, int, error) {
+	if w.rest == 0 {
+		return nDst, 0, nil
+	}
+
+	end := w.rest
+	if end > len(src) {
+		end = len(src)
+	}
+	nDst, nSrc, err := w.copy(dst, src, nDst, 0, end)
+	w.rest -= nSrc
+
+	return nDst, nSrc, err
+}
+
+// write writes b to dst[nDst:] and returns new nDst.
+// If dst is too short, the rest of b is kept until the next Transform call
+// and write returns transform.ErrShortDst.
+func (w *streamWriter) write(dst []byte, nDst int, b []byte) (int, error) {
+	n := copy(dst[nDst:], b)
+	if n < len(b) {
+		w.preDst = append([]byte(nil), b[n:]...)
+		return nDst + n, transform.ErrShortDst
+	}
+	return nDst + n, nil
+}
+
+// replace writes new as a replacement of src[src0:src1] of the current Transform call
+// and records the history.
+func (w *streamWriter) replace(dst []byte, nDst, src0, src1 int, new []byte) (int, error) {
+	w.record(src0, src1, nDst, nDst+len(new))
+	return w.write(dst, nDst, new)
+}
+
+// record records a history.
+// The ranges are relative to src and dst of the current Transform call.
+func (w *streamWriter) record(src0, src1, dst0, dst1 int) {
+	addHistory(w.history, w.offSrc+src0, w.offSrc+src1, w.offDst+dst0, w.offDst+dst1)
+}
+
+// done advances the offsets at the end of a Transform call.
+func (w *streamWriter) done(nDst, nSrc int) {
+	w.offDst += nDst
+	w.offSrc += nSrc
+}
+
+// matchPrefix reports whether src begins with p.
+// If src is a proper prefix of p and atEOF is false, matchPrefix reports short,
+// because src may begin with p with next several bytes.
+func matchPrefix(src, p []byte, atEOF bool) (match, short bool) {
+	if len(src) < len(p) {
+		return false, !atEOF && bytes.HasPrefix(p, src)
+	}
+	return bytes.HasPrefix(src, p), false
+}
@@ -0,0 +1,83 @@
+package transform_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"golang.org/x/text/transform"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+// transformChunks transforms input like transform.Reader
+// but it gives at most srcSize bytes as src and dstSize bytes as dst to each Transform call.
+// It is used for testing boundaries of chunks.
+func transformChunks(tr transform.Transformer, input string, srcSize, dstSize int) (string, error) {
+	var (
+		out  []byte
+		src  []byte
+		rest = []byte(input)
+		dst  = make([]byte, dstSize)
+	)
+
+	tr.Reset()
+	for noProgress := 0; noProgress < 10; {
+		n := srcSize - len(src)
+		if n > len(rest) {
+			n = len(rest)
+		}
+		src = append(src, rest[:n]...)
+		rest = rest[n:]
+		atEOF := len(rest) == 0
+
+		nDst, nSrc, err := tr.Transform(dst, src, atEOF)
+		out = append(out, dst[:nDst]...)
+		src = src[nSrc:]
+
+		if nDst == 0 && nSrc == 0 && n == 0 {
+			noProgress++
+		} else {
+			noProgress = 0
+		}
+
+		switch {
+		case err == nil:
+			if atEOF {
+				if len(src) != 0 {
+					return string(out), fmt.Errorf("%d bytes are not consumed at EOF", len(src))
+				}
+				return string(out), nil
+			}
+		case errors.Is(err, transform.ErrShortDst):
+		case errors.Is(err, transform.ErrShortSrc):
+			if atEOF || len(src) >= srcSize {
+				return string(out), err
+			}
+		default:
+			return string(out), err
+		}
+	}
+
+	return string(out), errors.New("no progress")
+}
+
+func TestTransformChunks(t *testing.T) {
+	cases := []struct {
+		srcSize, dstSize int
+	}{
+		{1, 1}, {3, 1}, {4, 2}, {100, 100},
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(fmt.Sprint(c.srcSize, "_", c.dstSize), func(t *testing.T) {
+			got, err := transformChunks(ReplaceString("abc", "ABCDE"), "xxabcabcx", c.srcSize, c.dstSize)
+			if err != nil {
+				t.Fatal("unexpected error:", err)
+			}
+			if got != "xxABCDEABCDEx" {
+				t.Errorf("the output is expected %q but %q", "xxABCDEABCDEx", got)
+			}
+		})
+	}
+}
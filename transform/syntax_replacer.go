@@ -0,0 +1,310 @@
+package transform
+
+import (
+	"bytes"
+
+	"golang.org/x/text/transform"
+)
+
+// Quote represents syntax of string literals.
+type Quote struct {
+	Open, Close string
+	// Escape is an escape character such as '\\'.
+	// The character and the next byte are a part of the string literal.
+	// If Escape is 0, the string literal does not have escape sequences.
+	Escape byte
+	// If Doubled is true, Close written twice is an escaped Close such as '' of SQL.
+	Doubled bool
+}
+
+// Syntax represents a lexical syntax of comments and string literals of a language.
+// Delimiters are tried from the longest one.
+type Syntax struct {
+	LineComments  []string    // such as "//"
+	BlockComments [][2]string // pairs of the beginning and the end such as {"/*", "*/"}
+	Quotes        []Quote
+}
+
+// Syntaxes of common languages.
+var (
+	// CSyntax is syntax of C-like languages such as C, C++, Java and JavaScript.
+	CSyntax = &Syntax{
+		LineComments:  []string{"//"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes: []Quote{
+			{Open: `"`, Close: `"`, Escape: '\\'},
+			{Open: `'`, Close: `'`, Escape: '\\'},
+		},
+	}
+
+	// ShellSyntax is syntax of shell scripts.
+	ShellSyntax = &Syntax{
+		LineComments: []string{"#"},
+		Quotes: []Quote{
+			{Open: `"`, Close: `"`, Escape: '\\'},
+			{Open: `'`, Close: `'`},
+		},
+	}
+
+	// PythonSyntax is syntax of Python.
+	PythonSyntax = &Syntax{
+		LineComments: []string{"#"},
+		Quotes: []Quote{
+			{Open: `"""`, Close: `"""`, Escape: '\\'},
+			{Open: `'''`, Close: `'''`, Escape: '\\'},
+			{Open: `"`, Close: `"`, Escape: '\\'},
+			{Open: `'`, Close: `'`, Escape: '\\'},
+		},
+	}
+
+	// SQLSyntax is syntax of SQL.
+	SQLSyntax = &Syntax{
+		LineComments:  []string{"--"},
+		BlockComments: [][2]string{{"/*", "*/"}},
+		Quotes: []Quote{
+			{Open: `'`, Close: `'`, Doubled: true},
+			{Open: `"`, Close: `"`, Doubled: true},
+		},
+	}
+)
+
+// SyntaxRegion represents kinds of regions of source code.
+// Regions can be combined with bitwise OR.
+type SyntaxRegion uint
+
+const (
+	// RegionCode represents code which is not a comment nor a string literal.
+	RegionCode SyntaxRegion = 1 << iota
+	// RegionComment represents comments except their delimiters.
+	RegionComment
+	// RegionString represents string literals except their quotes.
+	RegionString
+)
+
+type lexKind int
+
+const (
+	lexCode lexKind = iota
+	lexLineComment
+	lexBlockComment
+	lexString
+)
+
+// lexState is a state of the lexer.
+// i is an index of BlockComments or Quotes.
+type lexState struct {
+	kind lexKind
+	i    int
+}
+
+func (st lexState) region() SyntaxRegion {
+	switch st.kind {
+	case lexLineComment, lexBlockComment:
+		return RegionComment
+	case lexString:
+		return RegionString
+	}
+	return RegionCode
+}
+
+// step reads a token from the beginning of src and returns the next state and the length of the token.
+// If src is too short to decide the token and atEOF is false, step returns true as short.
+func (s *Syntax) step(st lexState, src []byte, atEOF bool) (next lexState, n int, short bool) {
+	// hasPrefix reports whether src begins with delim.
+	// It sets short if src may begin with delim with next several bytes.
+	hasPrefix := func(delim string) bool {
+		if len(src) < len(delim) {
+			if !atEOF && bytes.HasPrefix([]byte(delim), src) {
+				short = true
+			}
+			return false
+		}
+		return string(src[:len(delim)]) == delim
+	}
+
+	switch st.kind {
+	case lexCode:
+		next = st
+		open := func(delim string, kind lexKind, i int) {
+			if len(delim) > n && hasPrefix(delim) {
+				next, n = lexState{kind: kind, i: i}, len(delim)
+			}
+		}
+		for _, c := range s.LineComments {
+			open(c, lexLineComment, 0)
+		}
+		for i, c := range s.BlockComments {
+			open(c[0], lexBlockComment, i)
+		}
+		for i, q := range s.Quotes {
+			open(q.Open, lexString, i)
+		}
+		if n == 0 {
+			n = 1
+		}
+		return next, n, short
+	case lexLineComment:
+		if src[0] == '\n' {
+			return lexState{}, 1, false
+		}
+	case lexBlockComment:
+		if hasPrefix(s.BlockComments[st.i][1]) {
+			return lexState{}, len(s.BlockComments[st.i][1]), false
+		}
+	case lexString:
+		q := s.Quotes[st.i]
+		if q.Escape != 0 && src[0] == q.Escape {
+			if len(src) < 2 && !atEOF {
+				return st, 0, true
+			}
+			if len(src) >= 2 {
+				return st, 2, false
+			}
+		}
+
+		if hasPrefix(q.Close) {
+			if q.Doubled {
+				if hasPrefix(q.Close + q.Close) {
+					return st, 2 * len(q.Close), false
+				}
+				if short {
+					return st, 0, true
+				}
+			}
+			return lexState{}, len(q.Close), false
+		}
+	}
+
+	if short {
+		return st, 0, true
+	}
+
+	return st, 1, false
+}
+
+// SyntaxReplacer replaces byte data only in selected regions of source code,
+// such as comments or string literals.
+// It implements transform.Transformer.
+//
+// The regions are recognized by a small lexer which is configured by Syntax.
+// The lexer runs streaming alongside the replacing,
+// so a region can lie across src buffers of Transform calls.
+type SyntaxReplacer struct {
+	syntax  *Syntax
+	regions SyntaxRegion
+	rules   *ReplaceRules
+	state   lexState
+	w       streamWriter
+}
+
+var _ transform.Transformer = (*SyntaxReplacer)(nil)
+
+// NewSyntaxReplacer creates a new SyntaxReplacer which replaces byte data in regions
+// by the replacing rules which are indicated by ReplaceTable.
+// At each position the first rule in the table which matches is applied.
+// A match must not lie across a boundary of regions.
+//
+// If history is not nil, SyntaxReplacer records histories of replacing.
+func NewSyntaxReplacer(syntax *Syntax, regions SyntaxRegion, t ReplaceTable, history HistorySink) *SyntaxReplacer {
+	return &SyntaxReplacer{
+		syntax:  syntax,
+		regions: regions,
+		rules:   CompileReplaceTable(t),
+		w:       newStreamWriter(history),
+	}
+}
+
+// Reset implements transform.Transformer.Reset.
+func (r *SyntaxReplacer) Reset() {
+	r.w.reset()
+	r.state = lexState{}
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When end of src is not enough to decide a delimiter or a match of rules and atEOF is false,
+// the SyntaxReplacer stops to transform and returns transform.ErrShortSrc.
+func (r *SyntaxReplacer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { r.w.done(nDst, nSrc) }()
+
+	nDst, err = r.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	if nDst, nSrc, err = r.w.copyRest(dst, src, nDst); err != nil {
+		return nDst, nSrc, err
+	}
+
+	for nSrc < len(src) {
+		next, n, short := r.syntax.step(r.state, src[nSrc:], atEOF)
+		if short {
+			return nDst, nSrc, transform.ErrShortSrc
+		}
+
+		if next == r.state && r.regions&r.state.region() != 0 {
+			rule, short := r.match(src[nSrc:], atEOF)
+			if short {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+
+			if rule != -1 {
+				old, new := r.rules.At(rule)
+				nDst, err = r.w.replace(dst, nDst, nSrc, nSrc+len(old), new)
+				nSrc += len(old)
+				if err != nil {
+					return nDst, nSrc, err
+				}
+				continue
+			}
+		}
+
+		r.state = next
+		if nDst, nSrc, err = r.w.copyToken(dst, src, nDst, nSrc, n); err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	return nDst, nSrc, nil
+}
+
+// match returns the index of the first rule which matches at the beginning of src
+// without changing the state of the lexer, or -1.
+func (r *SyntaxReplacer) match(src []byte, atEOF bool) (rule int, short bool) {
+	for i, old := range r.rules.old {
+		if len(old) == 0 {
+			continue
+		}
+
+		if len(src) < len(old) {
+			if !atEOF && bytes.HasPrefix(old, src) {
+				return -1, true
+			}
+			continue
+		}
+
+		if !bytes.HasPrefix(src, old) {
+			continue
+		}
+
+		// the match must be in the current region
+		inRegion := true
+		for pos := 0; pos < len(old); {
+			next, n, short := r.syntax.step(r.state, src[pos:], atEOF)
+			if short {
+				return -1, true
+			}
+			if next != r.state {
+				inRegion = false
+				break
+			}
+			pos += n
+		}
+
+		if inRegion {
+			return i, false
+		}
+	}
+
+	return -1, false
+}
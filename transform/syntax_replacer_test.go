@@ -0,0 +1,102 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestSyntaxReplacer_Transform(t *testing.T) {
+	cases := []struct {
+		syntax  *Syntax
+		regions SyntaxRegion
+		table   ReplaceStringTable
+		input   string
+
+		expected string
+		history  []HistoryEntry
+	}{
+		{
+			syntax:   CSyntax,
+			regions:  RegionComment,
+			table:    ReplaceStringTable{"foo", "bar"},
+			input:    "foo(\"foo\"); // foo\n/* foo\nfoo */ foo",
+			expected: "foo(\"foo\"); // bar\n/* bar\nbar */ foo",
+			history:  []HistoryEntry{{15, 18, 15, 18}, {22, 25, 22, 25}, {26, 29, 26, 29}},
+		},
+		{
+			syntax:   CSyntax,
+			regions:  RegionString,
+			table:    ReplaceStringTable{"foo", "bar", `\"`, `'`},
+			input:    `foo("foo\"foo", 'foo'); // "foo"`,
+			expected: `foo("bar'bar", 'bar'); // "foo"`,
+			history:  []HistoryEntry{{5, 8, 5, 8}, {8, 10, 8, 9}, {10, 13, 9, 12}, {17, 20, 16, 19}},
+		},
+		{
+			syntax:   CSyntax,
+			regions:  RegionCode,
+			table:    ReplaceStringTable{"foo", "bar", `o"`, "X"},
+			input:    `foo("foo") /*foo*/`,
+			expected: `bar("foo") /*foo*/`,
+			history:  []HistoryEntry{{0, 3, 0, 3}},
+		},
+		{
+			// a match must not lie across a boundary of regions
+			syntax:   CSyntax,
+			regions:  RegionString,
+			table:    ReplaceStringTable{`a" `, "Y", `a\"`, "X"},
+			input:    `"a" "a\""`,
+			expected: `"a" "X"`,
+			history:  []HistoryEntry{{5, 8, 5, 6}},
+		},
+		{
+			syntax:   ShellSyntax,
+			regions:  RegionComment | RegionString,
+			table:    ReplaceStringTable{"old", "new"},
+			input:    "echo old 'old' \"old\" # old\nold",
+			expected: "echo old 'new' \"new\" # new\nold",
+			history:  []HistoryEntry{{10, 13, 10, 13}, {16, 19, 16, 19}, {23, 26, 23, 26}},
+		},
+		{
+			syntax:   PythonSyntax,
+			regions:  RegionString,
+			table:    ReplaceStringTable{"old", "new"},
+			input:    "old = \"\"\"old\n\"old\"\n\"\"\" + 'old' # old",
+			expected: "old = \"\"\"new\n\"new\"\n\"\"\" + 'new' # old",
+			history:  []HistoryEntry{{9, 12, 9, 12}, {14, 17, 14, 17}, {26, 29, 26, 29}},
+		},
+		{
+			syntax:   SQLSyntax,
+			regions:  RegionString,
+			table:    ReplaceStringTable{"old", "new"},
+			input:    "SELECT old FROM t WHERE a = 'it''s old' -- old",
+			expected: "SELECT old FROM t WHERE a = 'it''s new' -- old",
+			history:  []HistoryEntry{{35, 38, 35, 38}},
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {3, 1}, {4, 3}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				r := NewSyntaxReplacer(c.syntax, c.regions, c.table, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(r, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package transform
+
+import (
+	"unicode/utf8"
+
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// WidthMode represents a direction of width conversion.
+type WidthMode int
+
+const (
+	// WidthFold maps full-width runes to narrow ones and half-width runes to wide ones,
+	// like width.Fold. For example, "Ａ" becomes "A" and "ｶ" becomes "カ".
+	WidthFold WidthMode = iota
+	// WidthWiden maps runes to their wide forms, like width.Widen.
+	WidthWiden
+	// WidthNarrow maps runes to their narrow forms, like width.Narrow.
+	// Voiced katakana such as "ガ" are decomposed into "ｶﾞ".
+	WidthNarrow
+)
+
+// voiced sound marks
+const (
+	halfwidthVoiced     = '\uFF9E' // ﾞ
+	halfwidthSemiVoiced = '\uFF9F' // ﾟ
+	combiningVoiced     = '\u3099'
+	combiningSemiVoiced = '\u309A'
+	spacingVoiced       = '\u309B' // ゛
+	spacingSemiVoiced   = '\u309C' // ゜
+)
+
+// WidthTransformer converts widths of runes with the mapping of golang.org/x/text/width.
+// It implements transform.Transformer.
+//
+// Unlike width.Fold and width.Widen, a half-width katakana which is followed by
+// a half-width voiced sound mark (ﾞ or ﾟ) is combined into one full-width rune,
+// so "ｶﾞ" becomes "ガ". A voiced sound mark which cannot be combined becomes
+// a spacing one, "゛" or "゜".
+type WidthTransformer struct {
+	mode WidthMode
+	w    streamWriter
+}
+
+var _ transform.Transformer = (*WidthTransformer)(nil)
+
+// NewWidthTransformer creates a new WidthTransformer which converts widths by mode.
+//
+// If history is not nil, WidthTransformer records histories of converted runes.
+// A combined rune has one history whose source range contains the voiced sound mark.
+func NewWidthTransformer(mode WidthMode, history HistorySink) *WidthTransformer {
+	return &WidthTransformer{
+		mode: mode,
+		w:    newStreamWriter(history),
+	}
+}
+
+// Reset implements transform.Transformer.Reset.
+func (t *WidthTransformer) Reset() {
+	t.w.reset()
+}
+
+// Transform implements transform.Transformer.Transform.
+//
+// When src ends with an incomplete rune or a half-width katakana
+// which may be followed by a voiced sound mark and atEOF is false,
+// the WidthTransformer stops to transform and returns transform.ErrShortSrc.
+func (t *WidthTransformer) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	defer func() { t.w.done(nDst, nSrc) }()
+
+	nDst, err = t.w.flush(dst)
+	if err != nil {
+		return nDst, 0, err
+	}
+
+	for nSrc < len(src) {
+		out, n, short := t.convert(src[nSrc:], atEOF)
+		switch {
+		case short:
+			return nDst, nSrc, transform.ErrShortSrc
+		case out == nil:
+			nDst, nSrc, err = t.w.copy(dst, src, nDst, nSrc, nSrc+n)
+		default:
+			nDst, err = t.w.replace(dst, nDst, nSrc, nSrc+n, out)
+			nSrc += n
+		}
+
+		if err != nil {
+			return nDst, nSrc, err
+		}
+	}
+
+	return nDst, nSrc, nil
+}
+
+// convert converts a rune at the beginning of src.
+// It returns the converted bytes and the length of the converted source.
+// If the rune is not changed, out is nil.
+func (t *WidthTransformer) convert(src []byte, atEOF bool) (out []byte, n int, short bool) {
+	if !atEOF && !utf8.FullRune(src) {
+		return nil, 0, true
+	}
+
+	r, n := utf8.DecodeRune(src)
+	p := width.LookupRune(r)
+
+	if t.mode == WidthNarrow {
+		return t.narrow(r, p), n, false
+	}
+
+	var m rune
+	switch t.mode {
+	case WidthFold:
+		m = p.Folded()
+	case WidthWiden:
+		m = p.Wide()
+	}
+
+	switch {
+	case r == halfwidthVoiced:
+		m = spacingVoiced
+	case r == halfwidthSemiVoiced:
+		m = spacingSemiVoiced
+	case 0xFF66 <= r && r <= 0xFF9D: // half-width katakana
+		next := src[n:]
+		if !atEOF && (len(next) == 0 || !utf8.FullRune(next)) {
+			return nil, 0, true
+		}
+
+		mark, size := utf8.DecodeRune(next)
+		if c := composeVoiced(m, mark); c != 0 {
+			return []byte(string(c)), n + size, false
+		}
+	}
+
+	if m == 0 || m == r {
+		return nil, n, false
+	}
+
+	return []byte(string(m)), n, false
+}
+
+// narrow returns the narrow form of r.
+// A voiced katakana is decomposed into a half-width katakana and a voiced sound mark.
+func (t *WidthTransformer) narrow(r rune, p width.Properties) []byte {
+	switch r {
+	case spacingVoiced:
+		return []byte(string(halfwidthVoiced))
+	case spacingSemiVoiced:
+		return []byte(string(halfwidthSemiVoiced))
+	}
+
+	if m := p.Narrow(); m != 0 && m != r {
+		return []byte(string(m))
+	}
+
+	// decompose a voiced katakana such as "ガ"
+	d := []rune(norm.NFD.String(string(r)))
+	if len(d) != 2 {
+		return nil
+	}
+
+	base := width.LookupRune(d[0]).Narrow()
+	mark := width.LookupRune(d[1]).Narrow()
+	if base == 0 || mark == 0 {
+		return nil
+	}
+
+	return []byte(string([]rune{base, mark}))
+}
+
+// composeVoiced combines a full-width katakana and a half-width voiced sound mark.
+// It returns 0 if they cannot be combined.
+func composeVoiced(kana, mark rune) rune {
+	switch mark {
+	case halfwidthVoiced:
+		mark = combiningVoiced
+	case halfwidthSemiVoiced:
+		mark = combiningSemiVoiced
+	default:
+		return 0
+	}
+
+	c := []rune(norm.NFC.String(string([]rune{kana, mark})))
+	if len(c) != 1 {
+		return 0
+	}
+
+	return c[0]
+}
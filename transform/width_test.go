@@ -0,0 +1,72 @@
+package transform_test
+
+import (
+	"fmt"
+	"testing"
+
+	. "github.com/tenntenn/text/transform"
+)
+
+func TestWidthTransformer_Transform(t *testing.T) {
+	cases := []struct {
+		mode  WidthMode
+		input string
+
+		expected string
+		history  []HistoryEntry
+	}{
+		{
+			mode:     WidthFold,
+			input:    "Ｇｏ　ｶﾞｲﾄﾞ",
+			expected: "Go ガイド",
+			history: []HistoryEntry{
+				{0, 3, 0, 1}, {3, 6, 1, 2}, {6, 9, 2, 3},
+				{9, 15, 3, 6}, {15, 18, 6, 9}, {18, 24, 9, 12},
+			},
+		},
+		{
+			mode:     WidthFold,
+			input:    "ﾊﾟﾝﾞｱﾞ",
+			expected: "パン゛ア゛",
+			history: []HistoryEntry{
+				{0, 6, 0, 3}, {6, 9, 3, 6}, {9, 12, 6, 9}, {12, 15, 9, 12}, {15, 18, 12, 15},
+			},
+		},
+		{
+			mode:     WidthWiden,
+			input:    "Aｳﾞ",
+			expected: "Ａヴ",
+			history:  []HistoryEntry{{0, 1, 0, 3}, {1, 7, 3, 6}},
+		},
+		{
+			mode:     WidthNarrow,
+			input:    "Ａガパ。が",
+			expected: "Aｶﾞﾊﾟ｡が",
+			history:  []HistoryEntry{{0, 3, 0, 1}, {3, 6, 1, 7}, {6, 9, 7, 13}, {9, 12, 13, 16}},
+		},
+	}
+
+	for i, c := range cases {
+		for _, size := range [][2]int{{100, 100}, {6, 1}, {7, 4}} {
+			c := c
+			t.Run(fmt.Sprint(i, "_", size), func(t *testing.T) {
+				var history []HistoryEntry
+				w := NewWidthTransformer(c.mode, HistoryFunc(func(src0, src1, dst0, dst1 int) {
+					history = append(history, HistoryEntry{src0, src1, dst0, dst1})
+				}))
+				got, err := transformChunks(w, c.input, size[0], size[1])
+				if err != nil {
+					t.Fatal("unexpected error:", err)
+				}
+
+				if got != c.expected {
+					t.Errorf("the output is expected %q but %q", c.expected, got)
+				}
+
+				if g, e := fmt.Sprint(history), fmt.Sprint(c.history); g != e {
+					t.Errorf("histories are expected %s but %s", e, g)
+				}
+			})
+		}
+	}
+}